@@ -32,6 +32,7 @@ func NewApp() *App {
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx, a.cancelFunc = context.WithCancel(ctx)
+	backend.SetAppContext(a.ctx)
 
 	// Initialize database
 	err := database.GetDB().Init()
@@ -47,6 +48,12 @@ func (a *App) startup(ctx context.Context) {
 		a.logger.Error("Error checking tables:", err.Error())
 	}
 
+	// Remove media left behind by deleted jobs and abandoned uploads
+	err = backend.CleanupMedia()
+	if err != nil {
+		a.logger.Error("Error cleaning up media:", err.Error())
+	}
+
 	// Run CreateMovieFromQueue worker
 	a.wg.Add(1)
 	go func() {
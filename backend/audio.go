@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// WAVFormat describes the PCM stream of a WAV file.
+type WAVFormat struct {
+	AudioFormat   uint16
+	Channels      int
+	SampleRate    int
+	BitsPerSample int
+	DataOffset    int64
+	DataSize      int64
+}
+
+// Duration returns the playing time of the PCM data.
+func (f WAVFormat) Duration() time.Duration {
+	bytesPerSecond := int64(f.SampleRate * f.Channels * f.BitsPerSample / 8)
+	if bytesPerSecond == 0 {
+		return 0
+	}
+	return time.Duration(f.DataSize * int64(time.Second) / bytesPerSecond)
+}
+
+// parseWAVHeader reads the RIFF chunks up to the start of the data chunk.
+func parseWAVHeader(r io.ReadSeeker) (WAVFormat, error) {
+	var format WAVFormat
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return format, fmt.Errorf("failed to read WAV header: %w", err)
+	}
+	if !bytes.Equal(header[0:4], []byte("RIFF")) || !bytes.Equal(header[8:12], []byte("WAVE")) {
+		return format, errors.New("not a WAV file")
+	}
+
+	offset := int64(12)
+	foundFormat := false
+	chunk := make([]byte, 8)
+
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return format, fmt.Errorf("failed to find WAV data chunk: %w", err)
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		offset += 8
+
+		switch id {
+		case "fmt ":
+			fmtChunk := make([]byte, size)
+			if _, err := io.ReadFull(r, fmtChunk); err != nil || size < 16 {
+				return format, errors.New("invalid WAV fmt chunk")
+			}
+			format.AudioFormat = binary.LittleEndian.Uint16(fmtChunk[0:2])
+			format.Channels = int(binary.LittleEndian.Uint16(fmtChunk[2:4]))
+			format.SampleRate = int(binary.LittleEndian.Uint32(fmtChunk[4:8]))
+			format.BitsPerSample = int(binary.LittleEndian.Uint16(fmtChunk[14:16]))
+			foundFormat = true
+		case "data":
+			if !foundFormat {
+				return format, errors.New("WAV data chunk before fmt chunk")
+			}
+			format.DataOffset = offset
+			format.DataSize = size
+			return format, nil
+		default:
+			if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+				return format, fmt.Errorf("failed to skip WAV chunk: %w", err)
+			}
+		}
+
+		// Chunks are padded to an even size
+		offset += size
+		if size%2 == 1 {
+			if _, err := r.Seek(1, io.SeekCurrent); err != nil {
+				return format, fmt.Errorf("failed to skip WAV padding: %w", err)
+			}
+			offset++
+		}
+	}
+}
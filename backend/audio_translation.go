@@ -0,0 +1,161 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"infinity-subtitle/backend/database"
+)
+
+const (
+	UsageOperationAudioTranslation = "audio_translation"
+
+	// audioTranslationLanguage is the only language the translations endpoint produces
+	audioTranslationLanguage = "en"
+)
+
+// audioTranslator is a transcriber that can also turn speech in any language
+// straight into English text.
+type audioTranslator interface {
+	TranslateAudio(ctx context.Context, audioPath string) (Transcription, error)
+}
+
+// englishTranscriber lets an audioTranslator stand in for a Transcriber, so
+// English tracks are chunked and segmented like transcripts.
+type englishTranscriber struct {
+	translator audioTranslator
+}
+
+func (t englishTranscriber) Transcribe(ctx context.Context, audioPath string, language string) (Transcription, error) {
+	return t.translator.TranslateAudio(ctx, audioPath)
+}
+
+// transcriberCanTranslate reports whether the named transcriber, or the
+// default one if name is empty, offers the translations endpoint. Local
+// programs only transcribe.
+func transcriberCanTranslate(name string) bool {
+	if name == "" {
+		name = getTranscriberSettings().Default
+	}
+	return name == TranscriberOpenAI || name == TranscriberLocalServer
+}
+
+// translateAudioToSRT transcribes a recording into English SRT with the named
+// transcriber.
+func translateAudioToSRT(ctx context.Context, name string, key usageKey, audioPath string) (string, error) {
+	transcriber, err := newTranscriber(name, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to create transcriber: %w", err)
+	}
+	translator, ok := transcriber.(audioTranslator)
+	if !ok {
+		return "", fmt.Errorf("transcriber %q cannot translate audio", name)
+	}
+
+	srtContent, _, err := transcribeAudio(ctx, englishTranscriber{translator}, audioPath, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to translate audio: %w", err)
+	}
+	return srtContent, nil
+}
+
+// alignTrack fits the cues of a track timed on its own, such as the English
+// one from the translations endpoint, to the subtitles of a movie. Each cue
+// goes to the subtitle it overlaps most, or the nearest one, and cues sharing
+// a subtitle are joined.
+func alignTrack(subtitles []Subtitle, cues []SRTCue) map[int]string {
+	type span struct {
+		id         int
+		start, end time.Duration
+	}
+	spans := make([]span, 0, len(subtitles))
+	for _, subtitle := range subtitles {
+		start, startErr := parseTimestamp(subtitle.StartTime)
+		end, endErr := parseTimestamp(subtitle.EndTime)
+		if startErr != nil || endErr != nil {
+			continue
+		}
+		spans = append(spans, span{id: subtitle.ID, start: start, end: end})
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	texts := make(map[int][]string)
+	for _, cue := range cues {
+		text := strings.TrimSpace(cue.Text)
+		start, startErr := parseTimestamp(cue.StartTime)
+		end, endErr := parseTimestamp(cue.EndTime)
+		if text == "" || startErr != nil || endErr != nil {
+			continue
+		}
+		best, bestOverlap, bestGap := spans[0].id, time.Duration(-1), time.Duration(-1)
+		for _, s := range spans {
+			overlap := min(s.end, end) - max(s.start, start)
+			if overlap > 0 {
+				if overlap > bestOverlap {
+					best, bestOverlap = s.id, overlap
+				}
+				continue
+			}
+			if bestOverlap >= 0 {
+				continue
+			}
+			gap := -overlap
+			if bestGap < 0 || gap < bestGap {
+				best, bestGap = s.id, gap
+			}
+		}
+		texts[best] = append(texts[best], text)
+	}
+
+	aligned := make(map[int]string, len(texts))
+	for id, parts := range texts {
+		aligned[id] = strings.Join(parts, " ")
+	}
+	return aligned
+}
+
+// importAlignedTrack stores an SRT track in one language of a movie's
+// subtitles, aligned to their timing.
+func importAlignedTrack(movieID int, language string, srtContent string) error {
+	subtitles, err := getAllSubtitles(movieID)
+	if err != nil {
+		return err
+	}
+	aligned := alignTrack(subtitles, parseSRT(srtContent))
+	if len(aligned) == 0 {
+		return nil
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		UPDATE subtitles
+		SET content = json_set(COALESCE(content, '{}'), '$.' || ?, ?), quality = json_remove(quality, '$.' || ?),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE movie_id = ? AND id = ?
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for id, text := range aligned {
+		if _, err := stmt.Exec(language, text, language, movieID, id); err != nil {
+			return fmt.Errorf("failed to store %s subtitle: %w", language, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
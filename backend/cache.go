@@ -0,0 +1,233 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"infinity-subtitle/backend/database"
+	"infinity-subtitle/backend/logger"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// translationPromptVersion must be increased whenever the translation prompt
+// changes in a way that changes its output, so that older cache entries are
+// no longer used.
+const translationPromptVersion = 1
+
+// cacheLookupChunk keeps lookups below SQLite's limit of bound parameters.
+const cacheLookupChunk = 500
+
+// Cache manages the translation cache, which keeps every translation so that
+// re-running a translation doesn't call the API for lines seen before.
+type Cache struct{}
+
+// CacheStats summarises the translation cache.
+type CacheStats struct {
+	Entries int          `json:"entries"`
+	Hits    int          `json:"hits"`
+	Models  []CacheModel `json:"models"`
+	// SessionHits and SessionMisses count lookups since the app started
+	SessionHits   int64      `json:"session_hits"`
+	SessionMisses int64      `json:"session_misses"`
+	OldestAt      *time.Time `json:"oldest_at"`
+}
+
+type CacheModel struct {
+	Model   string `json:"model"`
+	Entries int    `json:"entries"`
+	Hits    int    `json:"hits"`
+}
+
+var cacheHits, cacheMisses atomic.Int64
+
+func NewCache() *Cache {
+	return &Cache{}
+}
+
+// cacheKey hashes everything the translation of a text depends on.
+func (ts *TranslationService) cacheKey(text TextToTranslate, sourceLang, targetLang string) string {
+	layout := "none"
+	if ts.constraints != nil {
+		layout = fmt.Sprintf("%dx%d", ts.constraints.MaxLines, ts.constraints.MaxCharsPerLine)
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strconv.Itoa(translationPromptVersion),
+		translationModel,
+		sourceLang,
+		targetLang,
+		layout,
+		strconv.Itoa(text.MaxChars),
+		ts.instruction,
+		strings.TrimSpace(text.SourceText),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// cachedTranslations returns the cached translations of the texts by id.
+// Cache failures are logged only, the texts are then translated as usual.
+func (ts *TranslationService) cachedTranslations(texts []TextToTranslate, sourceLang, targetLang string) map[int]string {
+	found := make(map[int]string)
+	if ts.refreshCache || len(texts) == 0 {
+		return found
+	}
+
+	db := database.GetDB()
+	for start := 0; start < len(texts); start += cacheLookupChunk {
+		chunk := texts[start:min(start+cacheLookupChunk, len(texts))]
+
+		idsByKey := make(map[string][]int, len(chunk))
+		args := make([]any, 0, len(chunk))
+		for _, text := range chunk {
+			key := ts.cacheKey(text, sourceLang, targetLang)
+			if _, ok := idsByKey[key]; !ok {
+				args = append(args, key)
+			}
+			idsByKey[key] = append(idsByKey[key], text.ID)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+
+		rows, err := db.Query("SELECT key, translation FROM translation_cache WHERE key IN ("+placeholders+")", args...)
+		if err != nil {
+			ts.logger.Error("failed to read translation cache: %v", err)
+			return found
+		}
+
+		var hitKeys []any
+		for rows.Next() {
+			var key, translation string
+			if err := rows.Scan(&key, &translation); err != nil {
+				ts.logger.Error("failed to read translation cache: %v", err)
+				continue
+			}
+			for _, id := range idsByKey[key] {
+				found[id] = translation
+			}
+			hitKeys = append(hitKeys, key)
+		}
+		rows.Close()
+
+		if len(hitKeys) > 0 {
+			_, err = db.Exec(`
+				UPDATE translation_cache SET hits = hits + 1, last_used_at = CURRENT_TIMESTAMP
+				WHERE key IN (`+strings.TrimSuffix(strings.Repeat("?,", len(hitKeys)), ",")+`)
+			`, hitKeys...)
+			if err != nil {
+				ts.logger.Error("failed to update translation cache hits: %v", err)
+			}
+		}
+	}
+
+	cacheHits.Add(int64(len(found)))
+	cacheMisses.Add(int64(len(texts) - len(found)))
+	return found
+}
+
+// storeTranslations adds translated texts to the cache.
+func (ts *TranslationService) storeTranslations(translations []TextToTranslate, sourceLang, targetLang string) {
+	db := database.GetDB()
+	for _, translation := range translations {
+		if translation.Translation == "" {
+			continue
+		}
+		_, err := db.Exec(`
+			INSERT INTO translation_cache (key, model, prompt_version, source_language, target_language,
+				source_text, translation)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(key) DO UPDATE SET
+				translation = excluded.translation,
+				created_at = CURRENT_TIMESTAMP
+		`, ts.cacheKey(translation, sourceLang, targetLang), translationModel, translationPromptVersion,
+			sourceLang, targetLang, translation.SourceText, translation.Translation)
+		if err != nil {
+			ts.logger.Error("failed to write translation cache: %v", err)
+			return
+		}
+	}
+}
+
+func (c *Cache) GetCacheStats() (CacheStats, error) {
+	stats := CacheStats{
+		Models:        []CacheModel{},
+		SessionHits:   cacheHits.Load(),
+		SessionMisses: cacheMisses.Load(),
+	}
+
+	db := database.GetDB()
+	rows, err := db.Query(`
+		SELECT model, COUNT(*), COALESCE(SUM(hits), 0)
+		FROM translation_cache
+		GROUP BY model
+		ORDER BY model
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get cache stats: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var model CacheModel
+		if err := rows.Scan(&model.Model, &model.Entries, &model.Hits); err != nil {
+			return stats, fmt.Errorf("failed to scan cache stats: %w", err)
+		}
+		stats.Models = append(stats.Models, model)
+		stats.Entries += model.Entries
+		stats.Hits += model.Hits
+	}
+	if err := rows.Err(); err != nil {
+		return stats, fmt.Errorf("failed to get cache stats: %w", err)
+	}
+
+	var oldest *string
+	err = db.QueryRow("SELECT MIN(created_at) FROM translation_cache").Scan(&oldest)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get cache stats: %w", err)
+	}
+	if oldest != nil {
+		if t, err := time.Parse(time.DateTime, *oldest); err == nil {
+			stats.OldestAt = &t
+		}
+	}
+
+	return stats, nil
+}
+
+// PurgeCache deletes cache entries older than the given number of days
+// and/or made by the given model. Zero days and an empty model match every
+// entry. It returns the number of entries deleted.
+func (c *Cache) PurgeCache(olderThanDays int, model string) (int64, error) {
+	if olderThanDays < 0 {
+		return 0, fmt.Errorf("days cannot be negative")
+	}
+
+	query := "DELETE FROM translation_cache WHERE 1 = 1"
+	args := []any{}
+	if olderThanDays > 0 {
+		query += " AND created_at < datetime('now', ?)"
+		args = append(args, fmt.Sprintf("-%d days", olderThanDays))
+	}
+	if model = strings.TrimSpace(model); model != "" {
+		query += " AND model = ?"
+		args = append(args, model)
+	}
+
+	db := database.GetDB()
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge translation cache: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge translation cache: %w", err)
+	}
+
+	if log, err := logger.GetLogger(); err == nil {
+		log.Info("Purged %d translation cache entries", deleted)
+	}
+
+	return deleted, nil
+}
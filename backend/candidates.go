@@ -0,0 +1,306 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"infinity-subtitle/backend/database"
+	"sort"
+)
+
+const (
+	SubtitleHistoryReasonCandidate = "candidate"
+
+	minCandidates = 2
+	maxCandidates = 5
+	// candidateTemperature samples varied wording for the same line
+	candidateTemperature = 1.0
+)
+
+// TranslationCandidate is an alternative translation of a subtitle.
+type TranslationCandidate struct {
+	Text  string `json:"text"`
+	Model string `json:"model"`
+	// Chain is the translation chain of the candidate, see Subtitle.TranslationChain
+	Chain []string `json:"chain"`
+	// OverLimit is set when the candidate is still over the subtitle limits
+	// after condensing
+	OverLimit bool `json:"over_limit"`
+}
+
+// CandidateRequest asks for alternative translations of the given subtitles.
+type CandidateRequest struct {
+	MovieID        int    `json:"movie_id"`
+	SourceLanguage string `json:"source_language"`
+	TargetLanguage string `json:"target_language"`
+	IDs            []int  `json:"ids"`
+	Count          int    `json:"count"`
+}
+
+// GenerateCandidates samples several translations of each selected subtitle
+// and stores the distinct ones as candidates. Subtitles without a translation
+// take the first candidate as their content.
+func (s Subtitle) GenerateCandidates(req CandidateRequest) ([]Subtitle, error) {
+	if len(req.IDs) == 0 {
+		return nil, errors.New("select at least one subtitle")
+	}
+	if req.SourceLanguage == "" || req.TargetLanguage == "" || req.SourceLanguage == req.TargetLanguage {
+		return nil, errors.New("source and target languages must be different")
+	}
+	if req.Count < minCandidates || req.Count > maxCandidates {
+		return nil, fmt.Errorf("number of candidates must be between %d and %d", minCandidates, maxCandidates)
+	}
+
+	ctx := context.Background()
+
+	movie, err := NewMovie().GetMovieByID(req.MovieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movie: %w", err)
+	}
+
+	ts, err := NewTranslationService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create translation service: %w", err)
+	}
+	defer ts.Close()
+	ts.usage = usageKey{MovieID: movie.ID, Language: req.TargetLanguage}
+	if constraints := getSubtitleConstraints(); constraints.enabled() {
+		ts.constraints = &constraints
+	}
+
+	subtitles, err := getAllSubtitles(movie.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[int]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		selected[id] = true
+	}
+
+	var texts []TextToTranslate
+	byID := make(map[int]Subtitle)
+	for _, subtitle := range subtitles {
+		if !selected[subtitle.ID] || subtitle.Content[req.SourceLanguage] == "" {
+			continue
+		}
+		text := TextToTranslate{ID: subtitle.ID, SourceText: subtitle.Content[req.SourceLanguage]}
+		if ts.constraints != nil {
+			text.MaxChars = ts.constraints.maxChars(subtitle.StartTime, subtitle.EndTime)
+		}
+		texts = append(texts, text)
+		byID[subtitle.ID] = subtitle
+	}
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("selected subtitles have no %s text", req.SourceLanguage)
+	}
+
+	candidates := make(map[int][]TranslationCandidate)
+	for start := 0; start < len(texts); start += translationBatchSize {
+		batch := texts[start:min(start+translationBatchSize, len(texts))]
+		batchCandidates, err := ts.sampleCandidates(ctx, batch, movie.Languages[req.SourceLanguage],
+			movie.Languages[req.TargetLanguage], req.Count)
+		if err != nil {
+			return nil, err
+		}
+		for id, list := range batchCandidates {
+			candidates[id] = list
+		}
+	}
+
+	chain := []string{req.SourceLanguage, req.TargetLanguage}
+	chainJson, err := json.Marshal(chain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal translation chain: %w", err)
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	updated := make([]Subtitle, 0, len(candidates))
+	for id, list := range candidates {
+		if len(list) == 0 {
+			continue
+		}
+		subtitle := byID[id]
+		for i := range list {
+			list[i].Chain = chain
+		}
+
+		candidatesJson, err := json.Marshal(list)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to marshal candidates: %w", err)
+		}
+		_, err = tx.Exec(`
+			UPDATE subtitles
+			SET candidates = json_set(COALESCE(candidates, '{}'), '$.' || ?, json(?))
+			WHERE id = ?
+		`, req.TargetLanguage, string(candidatesJson), id)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to store candidates: %w", err)
+		}
+		subtitle.Candidates[req.TargetLanguage] = list
+
+		if subtitle.Content[req.TargetLanguage] == "" {
+			_, err = tx.Exec(`
+				UPDATE subtitles
+				SET content = json_set(content, '$.' || ?, ?),
+					translation_chain = json_set(COALESCE(translation_chain, '{}'), '$.' || ?, json(?)),
+					quality = json_remove(quality, '$.' || ?),
+					updated_at = CURRENT_TIMESTAMP
+				WHERE id = ?
+			`, req.TargetLanguage, list[0].Text, req.TargetLanguage, string(chainJson), req.TargetLanguage, id)
+			if err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("failed to update subtitle: %w", err)
+			}
+			subtitle.Content[req.TargetLanguage] = list[0].Text
+		}
+
+		updated = append(updated, subtitle)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	sort.Slice(updated, func(i, j int) bool { return updated[i].SlNo < updated[j].SlNo })
+
+	return updated, nil
+}
+
+// sampleCandidates requests count sampled translations of a batch and keeps
+// the distinct texts per subtitle, in the order they were returned. With
+// subtitle limits set, candidates are condensed like translations are.
+func (ts *TranslationService) sampleCandidates(ctx context.Context, batch []TextToTranslate,
+	sourceLang, targetLang string, count int) (map[int][]TranslationCandidate, error) {
+	if err := ts.limiter.Wait(ctx, count*estimateBatchTokens(batch)); err != nil {
+		return nil, fmt.Errorf("rate limit exceeded: %w", err)
+	}
+
+	prompt, err := ts.translationPrompt(batch, sourceLang, targetLang)
+	if err != nil {
+		return nil, err
+	}
+
+	choices, err := ts.requestTranslationChoices(ctx, prompt, batch, UsageOperationTranslation,
+		count, candidateTemperature)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]TextToTranslate, len(batch))
+	for _, text := range batch {
+		byID[text.ID] = text
+	}
+
+	candidates := make(map[int][]TranslationCandidate)
+	seen := make(map[int]map[string]bool)
+	for _, choice := range choices {
+		if ts.constraints != nil {
+			// Candidates are held to the limits translations are condensed to
+			for i := range choice {
+				choice[i].SourceText = byID[choice[i].ID].SourceText
+				choice[i].MaxChars = byID[choice[i].ID].MaxChars
+			}
+			choice = ts.enforceConstraints(ctx, choice, targetLang)
+		}
+		for _, translation := range choice {
+			text := translation.Translation
+			if text == "" {
+				continue
+			}
+			if seen[translation.ID] == nil {
+				seen[translation.ID] = make(map[string]bool)
+			}
+			if seen[translation.ID][normalizeText(text)] {
+				continue
+			}
+			seen[translation.ID][normalizeText(text)] = true
+			candidates[translation.ID] = append(candidates[translation.ID], TranslationCandidate{
+				Text:      text,
+				Model:     translationModel,
+				OverLimit: ts.constraints != nil && !ts.constraints.fits(text, translation.MaxChars),
+			})
+		}
+	}
+
+	return candidates, nil
+}
+
+// ChooseCandidate makes a stored candidate the content of a subtitle in the
+// given language, with the candidate's translation chain. The replaced text
+// is kept in the subtitle history.
+func (s Subtitle) ChooseCandidate(subtitleID int, language string, index int) error {
+	db := database.GetDB()
+
+	subtitle, err := scanSubtitle(db.QueryRow("SELECT "+subtitleColumns+" FROM subtitles WHERE id = ?", subtitleID))
+	if err != nil {
+		return err
+	}
+
+	candidates := subtitle.Candidates[language]
+	if index < 0 || index >= len(candidates) {
+		return fmt.Errorf("subtitle %d has no %s candidate %d", subtitleID, language, index)
+	}
+	chosen := candidates[index].Text
+	previous := subtitle.Content[language]
+
+	// Candidates stored without their chain leave the language with none
+	var chain any
+	if len(candidates[index].Chain) > 0 {
+		chainJson, err := json.Marshal(candidates[index].Chain)
+		if err != nil {
+			return fmt.Errorf("failed to marshal translation chain: %w", err)
+		}
+		chain = string(chainJson)
+	}
+	if chosen == previous {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if previous != "" {
+		err = recordSubtitleHistory(tx, SubtitleHistory{
+			SubtitleID:   subtitleID,
+			Language:     language,
+			PreviousText: previous,
+			NewText:      chosen,
+			Reason:       SubtitleHistoryReasonCandidate,
+		})
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE subtitles
+		SET content = json_set(content, '$.' || ?, ?),
+			translation_chain = CASE WHEN ? IS NULL THEN json_remove(translation_chain, '$.' || ?)
+				ELSE json_set(COALESCE(translation_chain, '{}'), '$.' || ?, json(?)) END,
+			quality = json_remove(quality, '$.' || ?),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, language, chosen, chain, language, language, chain, language, subtitleID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to choose candidate: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,397 @@
+package backend
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// whisperUploadLimit stays a little below the 25 MB the API accepts
+	whisperUploadLimit = 24 << 20
+	// maxChunkDuration keeps timestamps of long recordings from drifting
+	maxChunkDuration = 10 * time.Minute
+	// chunkOverlap is transcribed by both neighbouring chunks
+	chunkOverlap = 3 * time.Second
+	// silenceSearchWindow is how far before the chunk end a cut point is looked for
+	silenceSearchWindow = time.Minute
+	// energyFrame is the resolution of the energy envelope
+	energyFrame = 20 * time.Millisecond
+	// silenceSmoothing averages the envelope so that a cut lands in a pause, not between syllables
+	silenceSmoothing = 500 * time.Millisecond
+
+	// chunkSampleRate is the rate chunks are written at; Whisper resamples to it anyway
+	chunkSampleRate = 16000
+)
+
+// audioChunk is a part of a recording, in time from the recording start.
+type audioChunk struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// needsChunking reports whether a WAV file is too big or too long to be
+// transcribed in one request.
+func needsChunking(format WAVFormat, size int64) bool {
+	return size > whisperUploadLimit || format.Duration() > maxChunkDuration
+}
+
+// wavFrameReader decodes the PCM frames of a WAV file to mono samples
+// between -1 and 1.
+type wavFrameReader struct {
+	format     WAVFormat
+	r          *bufio.Reader
+	frame      []byte
+	blockAlign int
+}
+
+func newWAVFrameReader(file io.ReaderAt, format WAVFormat, from time.Duration, to time.Duration) (*wavFrameReader, error) {
+	if format.AudioFormat != 1 && format.AudioFormat != 3 && format.AudioFormat != 0xFFFE {
+		return nil, fmt.Errorf("unsupported WAV encoding %d", format.AudioFormat)
+	}
+	switch format.BitsPerSample {
+	case 8, 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("unsupported WAV sample size of %d bits", format.BitsPerSample)
+	}
+
+	blockAlign := format.Channels * format.BitsPerSample / 8
+	if blockAlign == 0 {
+		return nil, errors.New("invalid WAV format")
+	}
+
+	startFrame := int64(from.Seconds() * float64(format.SampleRate))
+	endFrame := int64(to.Seconds() * float64(format.SampleRate))
+	start := format.DataOffset + startFrame*int64(blockAlign)
+	end := min(format.DataOffset+endFrame*int64(blockAlign), format.DataOffset+format.DataSize)
+
+	return &wavFrameReader{
+		format:     format,
+		r:          bufio.NewReaderSize(io.NewSectionReader(file, start, max(end-start, 0)), 64<<10),
+		frame:      make([]byte, blockAlign),
+		blockAlign: blockAlign,
+	}, nil
+}
+
+// next returns the next frame downmixed to mono, or io.EOF.
+func (w *wavFrameReader) next() (float64, error) {
+	if _, err := io.ReadFull(w.r, w.frame); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+
+	bytesPerSample := w.format.BitsPerSample / 8
+	sum := 0.0
+	for c := 0; c < w.format.Channels; c++ {
+		b := w.frame[c*bytesPerSample : (c+1)*bytesPerSample]
+		sum += decodeSample(b, w.format)
+	}
+	return sum / float64(w.format.Channels), nil
+}
+
+func decodeSample(b []byte, format WAVFormat) float64 {
+	switch format.BitsPerSample {
+	case 8:
+		return (float64(b[0]) - 128) / 128
+	case 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / 32768
+	case 24:
+		v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		return float64(v) / 8388608
+	default:
+		bits := binary.LittleEndian.Uint32(b)
+		if format.AudioFormat == 3 {
+			return float64(math.Float32frombits(bits))
+		}
+		return float64(int32(bits)) / 2147483648
+	}
+}
+
+// wavEnergy returns the RMS level of every energyFrame of the recording.
+func wavEnergy(file io.ReaderAt, format WAVFormat) ([]float64, error) {
+	reader, err := newWAVFrameReader(file, format, 0, format.Duration())
+	if err != nil {
+		return nil, err
+	}
+
+	frameSamples := max(int(float64(format.SampleRate)*energyFrame.Seconds()), 1)
+	energy := make([]float64, 0, int(format.Duration()/energyFrame)+1)
+	sum, count := 0.0, 0
+	for {
+		sample, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WAV samples: %w", err)
+		}
+		sum += sample * sample
+		count++
+		if count == frameSamples {
+			energy = append(energy, math.Sqrt(sum/float64(count)))
+			sum, count = 0, 0
+		}
+	}
+	if count > 0 {
+		energy = append(energy, math.Sqrt(sum/float64(count)))
+	}
+
+	return energy, nil
+}
+
+// planChunks cuts a recording into chunks of at most maxChunkDuration. Each
+// cut is placed at the quietest moment of the last silenceSearchWindow of a
+// chunk, and the next chunk starts chunkOverlap before it.
+func planChunks(energy []float64, total time.Duration) []audioChunk {
+	maxFrames := int(maxChunkDuration / energyFrame)
+	windowFrames := int(silenceSearchWindow / energyFrame)
+	overlapFrames := int(chunkOverlap / energyFrame)
+	smoothFrames := max(int(silenceSmoothing/energyFrame), 1)
+
+	frameTime := func(frame int) time.Duration {
+		return min(time.Duration(frame)*energyFrame, total)
+	}
+
+	var chunks []audioChunk
+	start := 0
+	for {
+		if len(energy)-start <= maxFrames {
+			return append(chunks, audioChunk{Start: frameTime(start), End: total})
+		}
+
+		target := start + maxFrames
+		cut := target
+		quietest := math.Inf(1)
+		for frame := target - windowFrames; frame <= target; frame++ {
+			level := 0.0
+			for i := frame - smoothFrames/2; i < frame+smoothFrames/2; i++ {
+				if i >= 0 && i < len(energy) {
+					level += energy[i]
+				}
+			}
+			// Ties go to the later frame so chunks stay as long as possible
+			if level <= quietest {
+				quietest = level
+				cut = frame
+			}
+		}
+
+		chunks = append(chunks, audioChunk{Start: frameTime(start), End: frameTime(cut)})
+		start = cut - overlapFrames
+	}
+}
+
+// writeChunkWAV writes a chunk of the recording as 16-bit mono WAV at
+// chunkSampleRate, which keeps a chunk of maxChunkDuration below the upload
+// limit whatever the source format.
+func writeChunkWAV(src io.ReaderAt, format WAVFormat, chunk audioChunk, dst *os.File) error {
+	reader, err := newWAVFrameReader(src, format, chunk.Start, chunk.End)
+	if err != nil {
+		return err
+	}
+
+	// The sizes are filled in once the samples are written
+	if err := writeWAVHeader(dst, 0); err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(dst)
+	step := float64(format.SampleRate) / chunkSampleRate
+	next := 0.0
+	previous := 0.0
+	written := 0
+	sample := make([]byte, 2)
+	for index := 0; ; index++ {
+		current, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read WAV samples: %w", err)
+		}
+
+		// Interpolate every output sample falling between the previous frame and this one
+		for ; next <= float64(index); next += step {
+			value := current - (current-previous)*(float64(index)-next)
+			value = max(-1, min(1, value))
+			binary.LittleEndian.PutUint16(sample, uint16(int16(value*32767)))
+			if _, err := out.Write(sample); err != nil {
+				return fmt.Errorf("failed to write audio chunk: %w", err)
+			}
+			written++
+		}
+		previous = current
+	}
+
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write audio chunk: %w", err)
+	}
+	if _, err := dst.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to write audio chunk: %w", err)
+	}
+	return writeWAVHeader(dst, written*2)
+}
+
+// writeWAVHeader writes the header of a 16-bit mono PCM file at chunkSampleRate.
+func writeWAVHeader(w io.Writer, dataSize int) error {
+	header := make([]byte, 44)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(36+dataSize))
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], 1)
+	binary.LittleEndian.PutUint16(header[22:24], 1)
+	binary.LittleEndian.PutUint32(header[24:28], chunkSampleRate)
+	binary.LittleEndian.PutUint32(header[28:32], chunkSampleRate*2)
+	binary.LittleEndian.PutUint16(header[32:34], 2)
+	binary.LittleEndian.PutUint16(header[34:36], 16)
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], uint32(dataSize))
+
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("failed to write WAV header: %w", err)
+	}
+	return nil
+}
+
+// convertToWAV decodes a compressed recording with ffmpeg so that it can be
+// split. The caller removes the returned file.
+func convertToWAV(ctx context.Context, audioPath string) (string, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return "", errors.New("audio files over 24 MB must be WAV, or ffmpeg must be installed to split them")
+	}
+
+	tmpFile, err := os.CreateTemp("", "audio-*.wav")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpFile.Close()
+
+	cmd := exec.CommandContext(ctx, ffmpeg, "-y", "-v", "error", "-i", audioPath,
+		"-ac", "1", "-ar", fmt.Sprint(chunkSampleRate), "-c:a", "pcm_s16le", tmpFile.Name())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("failed to convert audio to WAV: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return tmpFile.Name(), nil
+}
+
+// mergeChunkSRT offsets the SRT of every chunk by the chunk start and joins
+// them into one track. Where chunks overlap, cues centred before the middle
+// of the overlap are taken from the earlier chunk and the others from the
+// later one; a cue in the overlap repeating the text of the last cue of the
+// earlier chunk is dropped.
+// Speaker labels are kept; the programs that write them are not given chunks.
+func mergeChunkSRT(chunks []audioChunk, transcripts []string) string {
+	var merged []SRTCue
+	var ends []time.Duration
+	// chunkOf is the chunk each merged cue was taken from
+	var chunkOf []int
+
+	for i, chunk := range chunks {
+		from := time.Duration(0)
+		if i > 0 {
+			from = (chunk.Start + chunks[i-1].End) / 2
+		}
+		to := time.Duration(math.MaxInt64)
+		if i+1 < len(chunks) {
+			to = (chunks[i+1].Start + chunk.End) / 2
+		}
+
+		for _, cue := range parseSRT(transcripts[i]) {
+			start, startErr := parseTimestamp(cue.StartTime)
+			end, endErr := parseTimestamp(cue.EndTime)
+			if startErr != nil || endErr != nil {
+				continue
+			}
+			start += chunk.Start
+			end += chunk.Start
+			if middle := (start + end) / 2; middle < from || middle >= to {
+				continue
+			}
+
+			if n := len(merged); n > 0 {
+				last := normalizeText(merged[n-1].Text)
+				text := normalizeText(cue.Text)
+				// Only the overlap is heard twice; elsewhere a repeated line was said twice
+				inOverlap := i > 0 && chunkOf[n-1] == i-1 && start < chunks[i-1].End
+				if inOverlap && (strings.Contains(last, text) || strings.Contains(text, last)) {
+					if len(text) > len(last) {
+						merged[n-1].Text = cue.Text
+						merged[n-1].Speaker = cue.Speaker
+					}
+					ends[n-1] = max(ends[n-1], end)
+					continue
+				}
+				// Cues cut by the chunk border must not run into the next one
+				if ends[n-1] > start {
+					ends[n-1] = start
+				}
+			}
+
+			merged = append(merged, SRTCue{Text: cue.Text, Speaker: cue.Speaker, StartTime: formatTimestamp(start)})
+			ends = append(ends, end)
+			chunkOf = append(chunkOf, i)
+		}
+	}
+
+	for i := range merged {
+		merged[i].SlNo = i + 1
+		merged[i].EndTime = formatTimestamp(ends[i])
+	}
+	return formatSRT(merged)
+}
+
+// transcribeInChunks transcribes a WAV recording chunk by chunk and merges
+// the transcripts. The language is the one most chunks were detected in.
+func transcribeInChunks(ctx context.Context, transcribe func(ctx context.Context, path string) (string, string, error),
+	file *os.File, format WAVFormat) (string, string, error) {
+	energy, err := wavEnergy(file, format)
+	if err != nil {
+		return "", "", err
+	}
+
+	chunks := planChunks(energy, format.Duration())
+	transcripts := make([]string, len(chunks))
+	languages := make(map[string]int)
+	language := ""
+	for i, chunk := range chunks {
+		chunkFile, err := os.CreateTemp("", "audio-chunk-*.wav")
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		err = writeChunkWAV(file, format, chunk, chunkFile)
+		chunkFile.Close()
+		chunkLanguage := ""
+		if err == nil {
+			transcripts[i], chunkLanguage, err = transcribe(ctx, chunkFile.Name())
+		}
+		os.Remove(chunkFile.Name())
+		if err != nil {
+			return "", "", fmt.Errorf("chunk %d of %d (%s to %s): %w", i+1, len(chunks),
+				formatTimestamp(chunk.Start), formatTimestamp(chunk.End), err)
+		}
+
+		if chunkLanguage != "" {
+			languages[chunkLanguage]++
+			if languages[chunkLanguage] > languages[language] {
+				language = chunkLanguage
+			}
+		}
+	}
+
+	return mergeChunkSRT(chunks, transcripts), language, nil
+}
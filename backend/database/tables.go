@@ -113,67 +113,251 @@ func createMoviesQueueTable(db *sql.DB) error {
 	return nil
 }
 
-func CheckTablesExists() error {
-	db := GetDB()
-	logger, err := logger.GetLogger()
+func createAPIUsageTable(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS api_usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		movie_id INTEGER,
+		queue_id INTEGER,
+		language TEXT NOT NULL DEFAULT '',
+		model TEXT NOT NULL,
+		operation TEXT NOT NULL,
+		prompt_tokens INTEGER NOT NULL DEFAULT 0,
+		completion_tokens INTEGER NOT NULL DEFAULT 0,
+		audio_seconds REAL NOT NULL DEFAULT 0,
+		cost REAL NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+
 	if err != nil {
-		logger.Error("Error getting logger:", err)
-		return err
+		return fmt.Errorf("error creating api_usage table: %w", err)
 	}
 
-	var exists bool
-	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type='table' AND name='languages')").Scan(&exists)
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_api_usage_movie_id ON api_usage(movie_id)")
 	if err != nil {
-		logger.Error("Error checking languages table:", err)
-		return err
+		return fmt.Errorf("error creating api_usage movie_id index: %w", err)
 	}
 
-	if !exists {
-		err = createLanguagesTable(db.DB)
-		if err != nil {
-			logger.Error("Error creating languages table:", err)
-			return err
-		}
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_api_usage_queue_id ON api_usage(queue_id)")
+	if err != nil {
+		return fmt.Errorf("error creating api_usage queue_id index: %w", err)
 	}
 
-	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type='table' AND name='movies')").Scan(&exists)
+	return nil
+}
+
+func createModelPricesTable(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS model_prices (
+		model TEXT PRIMARY KEY,
+		input_per_million REAL NOT NULL DEFAULT 0,
+		output_per_million REAL NOT NULL DEFAULT 0,
+		per_audio_minute REAL NOT NULL DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+
 	if err != nil {
-		logger.Error("Error checking movies table:", err)
-		return err
+		return fmt.Errorf("error creating model_prices table: %w", err)
 	}
 
-	if !exists {
-		err = createMoviesTable(db.DB)
-		if err != nil {
-			logger.Error("Error creating movies table:", err)
-			return err
-		}
+	// Prices in USD as published by OpenAI, editable from the settings page
+	_, err = db.Exec(`
+	INSERT INTO model_prices (model, input_per_million, output_per_million, per_audio_minute) VALUES
+	('gpt-4o-mini', 0.15, 0.60, 0),
+	('gpt-4o', 2.50, 10.00, 0),
+	('whisper-1', 0, 0, 0.006)
+	`)
+
+	if err != nil {
+		log.Println("[x] Error inserting model prices:", err)
+	}
+
+	return nil
+}
+
+func createSubtitleHistoryTable(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS subtitle_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		subtitle_id INTEGER NOT NULL,
+		language TEXT NOT NULL,
+		previous_text TEXT NOT NULL,
+		new_text TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		instruction TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (subtitle_id) REFERENCES subtitles(id)
+	)`)
+
+	if err != nil {
+		return fmt.Errorf("error creating subtitle_history table: %w", err)
+	}
+
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_subtitle_history_subtitle_id ON subtitle_history(subtitle_id)")
+	if err != nil {
+		return fmt.Errorf("error creating subtitle_history subtitle_id index: %w", err)
+	}
+
+	return nil
+}
+
+func createTranslationCacheTable(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS translation_cache (
+		key TEXT PRIMARY KEY,
+		model TEXT NOT NULL,
+		prompt_version INTEGER NOT NULL,
+		source_language TEXT NOT NULL,
+		target_language TEXT NOT NULL,
+		source_text TEXT NOT NULL,
+		translation TEXT NOT NULL,
+		hits INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_used_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+
+	if err != nil {
+		return fmt.Errorf("error creating translation_cache table: %w", err)
+	}
+
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_translation_cache_model ON translation_cache(model)")
+	if err != nil {
+		return fmt.Errorf("error creating translation_cache model index: %w", err)
+	}
+
+	return nil
+}
+
+func createMovieMediaTable(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS movie_media (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		movie_id INTEGER NOT NULL,
+		path TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		file_type TEXT NOT NULL,
+		size INTEGER NOT NULL DEFAULT 0,
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		framerate REAL NOT NULL DEFAULT 0,
+		width INTEGER NOT NULL DEFAULT 0,
+		height INTEGER NOT NULL DEFAULT 0,
+		audio_channels INTEGER NOT NULL DEFAULT 0,
+		audio_stream_index INTEGER NOT NULL DEFAULT -1,
+		is_primary INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (movie_id, path),
+		FOREIGN KEY (movie_id) REFERENCES movies(id)
+	)`)
+
+	if err != nil {
+		return fmt.Errorf("error creating movie_media table: %w", err)
 	}
 
-	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type='table' AND name='subtitles')").Scan(&exists)
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_movie_media_path ON movie_media(path)")
 	if err != nil {
-		logger.Error("Error checking subtitles table:", err)
+		return fmt.Errorf("error creating movie_media path index: %w", err)
+	}
+
+	return nil
+}
+
+// columnMigrations lists columns added after a table was first released.
+// They are applied in order to databases created by older versions.
+var columnMigrations = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"movies_queue", "translation_errors", "JSON"},
+	{"subtitles", "quality", "JSON"},
+	{"movies", "pivot_language", "TEXT NOT NULL DEFAULT ''"},
+	{"movies_queue", "pivot_language", "TEXT NOT NULL DEFAULT ''"},
+	{"movies_queue", "transcriber", "TEXT NOT NULL DEFAULT ''"},
+	{"movies_queue", "detected_language", "TEXT NOT NULL DEFAULT ''"},
+	{"movies_queue", "media_path", "TEXT NOT NULL DEFAULT ''"},
+	{"movies_queue", "media_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"movies_queue", "media_hash", "TEXT NOT NULL DEFAULT ''"},
+	{"movies_queue", "stream_index", "INTEGER NOT NULL DEFAULT -1"},
+	{"movies_queue", "translate_audio", "INTEGER NOT NULL DEFAULT 0"},
+	{"movies_queue", "english_content", "TEXT NOT NULL DEFAULT ''"},
+	{"subtitles", "translation_chain", "JSON"},
+	{"subtitles", "candidates", "JSON"},
+	{"subtitles", "speaker", "TEXT NOT NULL DEFAULT ''"},
+	{"movies", "year", "INTEGER NOT NULL DEFAULT 0"},
+	{"movies", "series", "TEXT NOT NULL DEFAULT ''"},
+	{"movies", "season", "INTEGER NOT NULL DEFAULT 0"},
+	{"movies", "episode", "INTEGER NOT NULL DEFAULT 0"},
+	{"movies", "imdb_id", "TEXT NOT NULL DEFAULT ''"},
+	{"movies", "tmdb_id", "INTEGER NOT NULL DEFAULT 0"},
+	{"movies", "runtime_minutes", "INTEGER NOT NULL DEFAULT 0"},
+	{"movies", "genre", "TEXT NOT NULL DEFAULT ''"},
+	{"movies", "notes", "TEXT NOT NULL DEFAULT ''"},
+}
+
+func addColumnIfNotExists(db *sql.DB, table string, column string, definition string) error {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM pragma_table_info(?) WHERE name = ?)", table, column).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error checking column %s.%s: %w", table, column, err)
+	}
+
+	if exists {
+		return nil
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return fmt.Errorf("error adding column %s.%s: %w", table, column, err)
+	}
+
+	return nil
+}
+
+// tables lists every table with the function that creates it, in creation order.
+var tables = []struct {
+	name   string
+	create func(db *sql.DB) error
+}{
+	{"languages", createLanguagesTable},
+	{"movies", createMoviesTable},
+	{"subtitles", createSubtitlesTable},
+	{"movies_queue", createMoviesQueueTable},
+	{"api_usage", createAPIUsageTable},
+	{"model_prices", createModelPricesTable},
+	{"subtitle_history", createSubtitleHistoryTable},
+	{"translation_cache", createTranslationCacheTable},
+	{"movie_media", createMovieMediaTable},
+}
+
+func CheckTablesExists() error {
+	db := GetDB()
+	logger, err := logger.GetLogger()
+	if err != nil {
+		logger.Error("Error getting logger:", err)
 		return err
 	}
 
-	if !exists {
-		err = createSubtitlesTable(db.DB)
+	for _, table := range tables {
+		var exists bool
+		err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type='table' AND name=?)", table.name).Scan(&exists)
 		if err != nil {
-			logger.Error("Error creating subtitles table:", err)
+			logger.Error("Error checking %s table: %v", table.name, err)
 			return err
 		}
-	}
 
-	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type='table' AND name='movies_queue')").Scan(&exists)
-	if err != nil {
-		logger.Error("Error checking movies_queue table:", err)
-		return err
+		if !exists {
+			err = table.create(db.DB)
+			if err != nil {
+				logger.Error("Error creating %s table: %v", table.name, err)
+				return err
+			}
+		}
 	}
 
-	if !exists {
-		err = createMoviesQueueTable(db.DB)
+	for _, migration := range columnMigrations {
+		err = addColumnIfNotExists(db.DB, migration.table, migration.column, migration.definition)
 		if err != nil {
-			logger.Error("Error creating movies_queue table:", err)
+			logger.Error("Error migrating columns:", err)
 			return err
 		}
 	}
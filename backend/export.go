@@ -0,0 +1,307 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"infinity-subtitle/backend/database"
+)
+
+const (
+	ExportFormatSRT    = "srt"
+	ExportFormatWebVTT = "vtt"
+	ExportFormatASS    = "ass"
+	// ExportFormatMicroDVD is timed in frames of the movie's video
+	ExportFormatMicroDVD = "sub"
+	// ExportFormatTranscript is plain text by speaker turn, for scripts and dubbing
+	ExportFormatTranscript = "txt"
+)
+
+// assHeader styles ASS exports for the resolution of the movie's video:
+// the title, PlayResX, PlayResY, font size and margins.
+const assHeader = `[Script Info]
+Title: %s
+ScriptType: v4.00+
+WrapStyle: 0
+ScaledBorderAndShadow: yes
+PlayResX: %d
+PlayResY: %d
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,%d,&H00FFFFFF,&H000000FF,&H00000000,&H64000000,0,0,0,0,100,100,0,0,1,2,1,2,%d,%d,%d,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+// exportTarget is what a format may need besides the cues: the title, and
+// the video of the movie's primary media. Framerate, Width and Height are
+// zero when the movie has no linked video.
+type exportTarget struct {
+	Title     string
+	Framerate float64
+	Width     int
+	Height    int
+}
+
+// exportCue is a cue with text in the exported language.
+type exportCue struct {
+	SlNo int
+	// StartTime and EndTime are written to SRT as stored
+	StartTime string
+	EndTime   string
+	Start     time.Duration
+	End       time.Duration
+	Text      string
+	Speaker   string
+}
+
+// ExportSubtitleAs writes the subtitles of a movie in one language to a file
+// in the subtitles directory. Speakers are written as ASS actors, WebVTT
+// voice tags and transcript names; SRT has no place for them.
+func (s Subtitle) ExportSubtitleAs(movieId int, language string, format string) (ExportResponse, error) {
+	db := database.GetDB()
+	if db == nil {
+		return ExportResponse{}, errors.New("database connection is nil")
+	}
+
+	var write func(w io.Writer, target exportTarget, cues []exportCue) error
+	switch format {
+	case ExportFormatSRT:
+		write = writeSRTExport
+	case ExportFormatWebVTT:
+		write = writeWebVTTExport
+	case ExportFormatASS:
+		write = writeASSExport
+	case ExportFormatMicroDVD:
+		write = writeMicroDVDExport
+	case ExportFormatTranscript:
+		write = writeTranscriptExport
+	default:
+		return ExportResponse{}, fmt.Errorf("unknown export format %q", format)
+	}
+
+	// Get movie details
+	movie := NewMovie()
+	movie, err := movie.GetMovieByID(movieId)
+	if err != nil {
+		return ExportResponse{}, fmt.Errorf("failed to get movie: %w", err)
+	}
+
+	// Get all subtitles for the movie
+	subtitles, err := getAllSubtitles(movieId)
+	if err != nil {
+		return ExportResponse{}, err
+	}
+
+	cues, err := exportCues(subtitles, language, format != ExportFormatSRT)
+	if err != nil {
+		return ExportResponse{}, err
+	}
+
+	target := exportTarget{Title: movie.exportName()}
+	media, ok, err := primaryMedia(context.Background(), movieId)
+	if err != nil {
+		return ExportResponse{}, err
+	}
+	if ok {
+		target.Framerate, target.Width, target.Height = media.Framerate, media.Width, media.Height
+	}
+	if format == ExportFormatMicroDVD && target.Framerate <= 0 {
+		return ExportResponse{}, errors.New("MicroDVD needs the framerate of the movie's video; link a video file to the movie")
+	}
+
+	// Create subtitles directory if it doesn't exist
+	subtitlesDir := "subtitles"
+	if err := os.MkdirAll(subtitlesDir, 0755); err != nil {
+		return ExportResponse{}, fmt.Errorf("failed to create subtitles directory: %w", err)
+	}
+
+	// Create movie directory, a directory per season for series
+	movieDir := filepath.Join(subtitlesDir, movie.exportDir())
+	if err := os.MkdirAll(movieDir, 0755); err != nil {
+		return ExportResponse{}, fmt.Errorf("failed to create movie directory: %w", err)
+	}
+
+	fileName := safeFileName(fmt.Sprintf("%s - %s", movie.exportName(), movie.Languages[language])) + "." + format
+	filePath := filepath.Join(movieDir, fileName)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return ExportResponse{}, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	if err := write(file, target, cues); err != nil {
+		return ExportResponse{}, fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	// Get absolute path
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return ExportResponse{}, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	return ExportResponse{
+		FilePath: absPath,
+	}, nil
+}
+
+// exportCues collects the subtitles that have text in a language. Formats
+// that rewrite timestamps need them valid.
+func exportCues(subtitles []Subtitle, language string, needTimes bool) ([]exportCue, error) {
+	var cues []exportCue
+	for _, subtitle := range subtitles {
+		content := strings.TrimSpace(subtitle.Content[language])
+		if content == "" {
+			continue
+		}
+		start, startErr := parseTimestamp(subtitle.StartTime)
+		end, endErr := parseTimestamp(subtitle.EndTime)
+		if (startErr != nil || endErr != nil) && needTimes {
+			return nil, fmt.Errorf("subtitle %d has an invalid time", subtitle.SlNo)
+		}
+		cues = append(cues, exportCue{
+			SlNo:      subtitle.SlNo,
+			StartTime: subtitle.StartTime,
+			EndTime:   subtitle.EndTime,
+			Start:     start,
+			End:       end,
+			Text:      content,
+			Speaker:   subtitle.Speaker,
+		})
+	}
+	return cues, nil
+}
+
+func writeSRTExport(w io.Writer, target exportTarget, cues []exportCue) error {
+	for _, cue := range cues {
+		_, err := fmt.Fprintf(w, "%d\n%s --> %s\n%s\n\n", cue.SlNo, cue.StartTime, cue.EndTime, cue.Text)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeWebVTTExport writes the cues as WebVTT. A blank line would end a cue
+// and an arrow would make the line a timing line, so cue text has neither.
+func writeWebVTTExport(w io.Writer, target exportTarget, cues []exportCue) error {
+	if _, err := io.WriteString(w, "WEBVTT\n\n"); err != nil {
+		return err
+	}
+	escaper := strings.NewReplacer("-->", "->", "&", "&amp;", "<", "&lt;", ">", "&gt;")
+	for _, cue := range cues {
+		var lines []string
+		for _, line := range strings.Split(strings.ReplaceAll(cue.Text, "\r", ""), "\n") {
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+		text := escaper.Replace(strings.Join(lines, "\n"))
+		if cue.Speaker != "" {
+			text = "<v " + cue.Speaker + ">" + text
+		}
+		_, err := fmt.Fprintf(w, "%s --> %s\n%s\n\n", formatVTTTimestamp(cue.Start), formatVTTTimestamp(cue.End), text)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeASSExport styles the cues for the movie's video, or for 1080p if its
+// resolution is unknown. Sizes are chosen for 1080p and scaled with the height.
+func writeASSExport(w io.Writer, target exportTarget, cues []exportCue) error {
+	width, height := target.Width, target.Height
+	if width <= 0 || height <= 0 {
+		width, height = 1920, 1080
+	}
+	scale := func(size int) int { return max(int(math.Round(float64(size*height)/1080)), 1) }
+	_, err := fmt.Fprintf(w, assHeader, target.Title, width, height, scale(56), scale(60), scale(60), scale(50))
+	if err != nil {
+		return err
+	}
+	// Braces start override tags, so they are shown as parentheses
+	escaper := strings.NewReplacer("\n", `\N`, "{", "(", "}", ")")
+	// A comma in the actor would shift the fields after it into the text
+	actor := strings.NewReplacer(",", " ", "\n", " ")
+	for _, cue := range cues {
+		_, err := fmt.Fprintf(w, "Dialogue: 0,%s,%s,Default,%s,0,0,0,,%s\n", formatASSTimestamp(cue.Start),
+			formatASSTimestamp(cue.End), actor.Replace(cue.Speaker), escaper.Replace(cue.Text))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMicroDVDExport writes cues as {start frame}{end frame}text, with lines
+// separated by |. The first line declares the framerate, which players read.
+func writeMicroDVDExport(w io.Writer, target exportTarget, cues []exportCue) error {
+	framerate := strconv.FormatFloat(math.Round(target.Framerate*1000)/1000, 'f', -1, 64)
+	if _, err := fmt.Fprintf(w, "{1}{1}%s\n", framerate); err != nil {
+		return err
+	}
+	// Braces start control codes, so they are shown as parentheses
+	escaper := strings.NewReplacer("\r", "", "\n", "|", "{", "(", "}", ")")
+	for _, cue := range cues {
+		_, err := fmt.Fprintf(w, "{%d}{%d}%s\n", frameNumber(cue.Start, target.Framerate),
+			frameNumber(cue.End, target.Framerate), escaper.Replace(cue.Text))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// frameNumber returns the frame shown at a time.
+func frameNumber(d time.Duration, framerate float64) int64 {
+	return int64(math.Round(max(d.Seconds(), 0) * framerate))
+}
+
+// writeTranscriptExport writes a paragraph per speaker turn, starting with
+// its time and speaker.
+func writeTranscriptExport(w io.Writer, target exportTarget, cues []exportCue) error {
+	for i := 0; i < len(cues); {
+		turn := []string{strings.Join(strings.Fields(cues[i].Text), " ")}
+		j := i + 1
+		for j < len(cues) && cues[j].Speaker == cues[i].Speaker {
+			turn = append(turn, strings.Join(strings.Fields(cues[j].Text), " "))
+			j++
+		}
+
+		// The time is given to the second, 00:01:02
+		line := fmt.Sprintf("[%s] ", formatTimestamp(cues[i].Start)[:8])
+		if cues[i].Speaker != "" {
+			line += cues[i].Speaker + ": "
+		}
+		if _, err := fmt.Fprintf(w, "%s%s\n\n", line, strings.Join(turn, " ")); err != nil {
+			return err
+		}
+		i = j
+	}
+	return nil
+}
+
+// formatVTTTimestamp converts a duration to a WebVTT timestamp, 00:01:02.345.
+func formatVTTTimestamp(d time.Duration) string {
+	return strings.Replace(formatTimestamp(d), ",", ".", 1)
+}
+
+// formatASSTimestamp converts a duration to an ASS timestamp, 0:01:02.34.
+func formatASSTimestamp(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	centis := d.Milliseconds() / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", centis/360000, centis/6000%60, centis/100%60, centis%100)
+}
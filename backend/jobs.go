@@ -0,0 +1,204 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	JobKindTranslation      = "translation"
+	JobKindQueueTranslation = "queue_translation"
+)
+
+// Job is a long running translation that can be followed and cancelled
+// from the UI.
+type Job struct {
+	ID        int                    `json:"id"`
+	Kind      string                 `json:"kind"`
+	MovieID   int                    `json:"movie_id"`
+	QueueID   int                    `json:"queue_id"`
+	StartedAt time.Time              `json:"started_at"`
+	Progress  map[string]JobProgress `json:"progress"`
+}
+
+// JobProgress is emitted as "translation-progress" after every batch.
+type JobProgress struct {
+	JobID    int    `json:"job_id"`
+	MovieID  int    `json:"movie_id"`
+	QueueID  int    `json:"queue_id"`
+	Language string `json:"language"`
+	Done     int    `json:"done"`
+	Total    int    `json:"total"`
+	// ETASeconds estimates the time left from the pace so far, 0 if unknown
+	ETASeconds float64 `json:"eta_seconds"`
+}
+
+type runningJob struct {
+	mu        sync.Mutex
+	job       Job
+	cancel    context.CancelFunc
+	cancelled bool
+	started   map[string]time.Time
+}
+
+type jobContextKey struct{}
+
+var (
+	jobsMu    sync.Mutex
+	jobs      = make(map[int]*runningJob)
+	lastJobID int
+
+	// appCtx is the Wails context used to emit events
+	appCtx context.Context
+)
+
+func NewJob() *Job {
+	return &Job{}
+}
+
+// SetAppContext stores the Wails context so that jobs started from bound
+// methods can emit events.
+func SetAppContext(ctx context.Context) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	appCtx = ctx
+}
+
+func emitEvent(name string, data ...any) {
+	jobsMu.Lock()
+	ctx := appCtx
+	jobsMu.Unlock()
+	if ctx != nil {
+		runtime.EventsEmit(ctx, name, data...)
+	}
+}
+
+// startJob registers a job and returns the context its work must run on.
+// The context carries the job so that translations can report progress.
+func startJob(parent context.Context, kind string, movieID int, queueID int) (*runningJob, context.Context) {
+	ctx, cancel := context.WithCancel(parent)
+
+	jobsMu.Lock()
+	lastJobID++
+	job := &runningJob{
+		job: Job{
+			ID:        lastJobID,
+			Kind:      kind,
+			MovieID:   movieID,
+			QueueID:   queueID,
+			StartedAt: time.Now(),
+			Progress:  make(map[string]JobProgress),
+		},
+		cancel:  cancel,
+		started: make(map[string]time.Time),
+	}
+	jobs[job.job.ID] = job
+	jobsMu.Unlock()
+
+	emitEvent("job-started", job.snapshot())
+	return job, context.WithValue(ctx, jobContextKey{}, job)
+}
+
+func jobFromContext(ctx context.Context) *runningJob {
+	job, _ := ctx.Value(jobContextKey{}).(*runningJob)
+	return job
+}
+
+// finish unregisters the job and reports whether it was cancelled.
+func (j *runningJob) finish() bool {
+	jobsMu.Lock()
+	delete(jobs, j.job.ID)
+	jobsMu.Unlock()
+	j.cancel()
+
+	j.mu.Lock()
+	cancelled := j.cancelled
+	j.mu.Unlock()
+
+	emitEvent("job-finished", j.snapshot(), cancelled)
+	return cancelled
+}
+
+// wasCancelled reports whether CancelJob was called for the job.
+func (j *runningJob) wasCancelled() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.cancelled
+}
+
+func (j *runningJob) snapshot() Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job := j.job
+	job.Progress = make(map[string]JobProgress, len(j.job.Progress))
+	for language, progress := range j.job.Progress {
+		job.Progress[language] = progress
+	}
+	return job
+}
+
+// reportProgress records how many cues of a language are done and emits it.
+func (j *runningJob) reportProgress(language string, done int, total int) {
+	j.mu.Lock()
+	now := time.Now()
+	started, ok := j.started[language]
+	if !ok {
+		started = now
+		j.started[language] = now
+	}
+
+	progress := JobProgress{
+		JobID:    j.job.ID,
+		MovieID:  j.job.MovieID,
+		QueueID:  j.job.QueueID,
+		Language: language,
+		Done:     done,
+		Total:    total,
+	}
+	if done > 0 && done < total {
+		perCue := now.Sub(started).Seconds() / float64(done)
+		progress.ETASeconds = perCue * float64(total-done)
+	}
+	j.job.Progress[language] = progress
+	j.mu.Unlock()
+
+	emitEvent("translation-progress", progress)
+}
+
+// ListJobs returns the running jobs, oldest first.
+func (j *Job) ListJobs() []Job {
+	jobsMu.Lock()
+	running := make([]*runningJob, 0, len(jobs))
+	for _, job := range jobs {
+		running = append(running, job)
+	}
+	jobsMu.Unlock()
+
+	list := make([]Job, 0, len(running))
+	for _, job := range running {
+		list = append(list, job.snapshot())
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].ID < list[b].ID })
+	return list
+}
+
+// CancelJob stops a running job. Batches already translated stay saved.
+func (j *Job) CancelJob(id int) error {
+	jobsMu.Lock()
+	job, ok := jobs[id]
+	jobsMu.Unlock()
+	if !ok {
+		return fmt.Errorf("job %d is not running", id)
+	}
+
+	job.mu.Lock()
+	job.cancelled = true
+	job.mu.Unlock()
+	job.cancel()
+	return nil
+}
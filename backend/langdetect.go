@@ -0,0 +1,251 @@
+package backend
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// AutoLanguage lets the queue detect the source language of an upload
+	AutoLanguage = "auto"
+
+	// minDetectionLetters is the least text a language is guessed from
+	minDetectionLetters = 20
+	// maxDetectionRunes bounds the text read from long subtitles
+	maxDetectionRunes = 20000
+	// minMismatchConfidence keeps uncertain guesses from raising warnings
+	minMismatchConfidence = 0.5
+)
+
+// LanguageDetection is the language found in an upload, next to the one the
+// uploader declared.
+type LanguageDetection struct {
+	Name     string `json:"name"`
+	Declared string `json:"declared"`
+	// Detected is a language code, empty if the language could not be told
+	Detected   string  `json:"detected"`
+	Confidence float64 `json:"confidence"`
+	Mismatch   bool    `json:"mismatch"`
+}
+
+// scriptLanguages names the language of texts written in scripts used by
+// (nearly) one language.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	code  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Georgian, "ka"},
+	{unicode.Armenian, "hy"},
+	{unicode.Bengali, "bn"},
+	{unicode.Tamil, "ta"},
+	{unicode.Khmer, "km"},
+	{unicode.Lao, "lo"},
+	{unicode.Myanmar, "my"},
+}
+
+// commonWords are frequent short words of languages written in the Latin
+// alphabet. A text is scored by how many of its words are in each list.
+var commonWords = map[string][]string{
+	"en": {"the", "and", "you", "is", "to", "of", "it", "that", "what", "this", "i'm", "don't", "he", "she",
+		"we", "are", "was", "with", "have", "not", "for", "my", "your", "me", "just", "know", "be", "do"},
+	"es": {"el", "la", "que", "de", "y", "es", "no", "en", "lo", "un", "una", "por", "qué", "con", "para",
+		"los", "las", "pero", "está", "yo", "tú", "eso", "muy", "bien", "aquí", "sí", "como", "se"},
+	"fr": {"le", "la", "les", "et", "est", "je", "tu", "vous", "que", "pas", "de", "un", "une", "il", "elle",
+		"c'est", "ce", "qui", "nous", "dans", "pour", "mais", "avec", "oui", "non", "sur", "ne", "des"},
+	"de": {"der", "die", "das", "und", "ist", "ich", "du", "nicht", "sie", "es", "wir", "ein", "eine", "zu",
+		"mit", "was", "auf", "den", "dem", "ja", "nein", "aber", "hier", "sind", "haben", "wie", "so"},
+	"it": {"il", "la", "che", "di", "e", "è", "non", "un", "una", "per", "sono", "io", "ho", "mi", "ti",
+		"questo", "cosa", "come", "lo", "gli", "ma", "bene", "sì", "con", "del", "della", "qui"},
+	"pt": {"o", "a", "que", "de", "e", "é", "não", "um", "uma", "eu", "você", "para", "com", "os", "as",
+		"isso", "está", "do", "da", "em", "mas", "sim", "muito", "aqui", "tem", "meu", "se"},
+	"nl": {"de", "het", "een", "en", "is", "ik", "je", "niet", "dat", "wat", "van", "we", "zijn", "op",
+		"te", "met", "hij", "ze", "maar", "ja", "nee", "hier", "er", "dit", "kan", "heb"},
+	"sv": {"och", "att", "det", "är", "jag", "du", "inte", "en", "ett", "som", "på", "med", "har", "vi",
+		"han", "hon", "vad", "för", "men", "ja", "nej", "här", "kan", "till", "den"},
+	"pl": {"nie", "to", "się", "jest", "że", "na", "co", "jak", "ja", "ty", "tak", "w", "z", "i", "do",
+		"mnie", "tego", "ale", "czy", "już", "mi", "jestem", "tu", "o"},
+	"tr": {"bir", "ve", "bu", "ne", "ben", "sen", "çok", "için", "değil", "mi", "da", "de", "var", "yok",
+		"ama", "evet", "hayır", "şey", "o", "gibi", "daha", "neden", "nasıl"},
+	"id": {"yang", "dan", "ini", "itu", "aku", "kamu", "tidak", "apa", "saya", "di", "ke", "ada", "dia",
+		"bisa", "kita", "karena", "saja", "sudah", "belum", "nggak", "kalian", "mau", "juga"},
+	"ms": {"yang", "dan", "ini", "itu", "aku", "awak", "tidak", "apa", "saya", "di", "ke", "ada", "dia",
+		"boleh", "kita", "kerana", "sahaja", "sudah", "belum", "tak", "kau", "mahu", "juga"},
+	"vi": {"không", "tôi", "là", "của", "và", "có", "anh", "em", "này", "được", "một", "cho", "với", "những",
+		"người", "đi", "rồi", "gì", "như", "thì", "đó", "chúng", "cái", "làm"},
+}
+
+// relatedLanguages are close enough that telling them apart from a subtitle
+// is unreliable, so confusing them is not reported as a mismatch.
+var relatedLanguages = map[string]string{
+	"id": "ms",
+	"ms": "id",
+}
+
+// detectLanguage guesses the language of a text offline, from the scripts it
+// uses and, for Latin text, its most common words. It returns an empty code
+// when the text is too short or unclear.
+func detectLanguage(text string) (string, float64) {
+	if utf8.RuneCountInString(text) > maxDetectionRunes {
+		text = string([]rune(text)[:maxDetectionRunes])
+	}
+
+	letters := 0
+	latin, cyrillic, arabic, han, kana := 0, 0, 0, 0, 0
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		default:
+			for _, script := range scriptLanguages {
+				if unicode.Is(script.table, r) {
+					scripts[script.code]++
+					break
+				}
+			}
+		}
+	}
+	if letters < minDetectionLetters {
+		return "", 0
+	}
+	share := func(count int) float64 { return float64(count) / float64(letters) }
+
+	// Japanese mixes kana with kanji, Chinese has no kana
+	if cjk := han + kana; share(cjk) > 0.5 {
+		if float64(kana) > float64(cjk)*0.1 {
+			return "ja", share(cjk)
+		}
+		return "zh", share(cjk)
+	}
+	for code, count := range scripts {
+		if share(count) > 0.5 {
+			return code, share(count)
+		}
+	}
+	if share(cyrillic) > 0.5 {
+		if strings.ContainsAny(text, "іїєґІЇЄҐ") {
+			return "uk", share(cyrillic)
+		}
+		return "ru", share(cyrillic)
+	}
+	if share(arabic) > 0.5 {
+		if strings.ContainsAny(text, "پچژگ") {
+			return "fa", share(arabic)
+		}
+		return "ar", share(arabic)
+	}
+	if share(latin) > 0.5 {
+		code, confidence := detectLatinLanguage(text)
+		return code, confidence * share(latin)
+	}
+
+	return "", 0
+}
+
+// detectLatinLanguage scores the words of the text against commonWords. The
+// confidence is the lead of the best language over the runner-up.
+func detectLatinLanguage(text string) (string, float64) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) == 0 {
+		return "", 0
+	}
+
+	scores := make(map[string]int)
+	for code, list := range commonWords {
+		common := make(map[string]bool, len(list))
+		for _, word := range list {
+			common[word] = true
+		}
+		for _, word := range words {
+			if common[word] {
+				scores[code]++
+			}
+		}
+	}
+
+	best := ""
+	for code, score := range scores {
+		if best == "" || score > scores[best] || (score == scores[best] && code < best) {
+			best = code
+		}
+	}
+	second := 0
+	for code, score := range scores {
+		if code != best {
+			second = max(second, score)
+		}
+	}
+
+	// Subtitles are around a third common words; much fewer means another language
+	if best == "" || float64(scores[best]) < float64(len(words))*0.1 {
+		return "", 0
+	}
+	return best, float64(scores[best]-second) / float64(scores[best])
+}
+
+// detectSubtitleLanguage detects the language of the text of SRT content.
+func detectSubtitleLanguage(content string) (string, float64) {
+	var b strings.Builder
+	for _, cue := range parseSRT(content) {
+		b.WriteString(cue.Text)
+		if b.Len() > maxDetectionRunes*4 {
+			break
+		}
+	}
+	return detectLanguage(b.String())
+}
+
+// languageMismatch reports whether a confident detection contradicts the
+// declared language.
+func languageMismatch(declared string, detected string, confidence float64) bool {
+	if declared == "" || declared == AutoLanguage || detected == "" || confidence < minMismatchConfidence {
+		return false
+	}
+	base, _, _ := strings.Cut(strings.ToLower(declared), "-")
+	return base != detected && relatedLanguages[base] != detected
+}
+
+// whisperLanguages maps the language names in transcription responses to codes.
+var whisperLanguages = map[string]string{
+	"english": "en", "chinese": "zh", "japanese": "ja", "korean": "ko", "thai": "th",
+	"indonesian": "id", "malay": "ms", "vietnamese": "vi", "hindi": "hi", "spanish": "es",
+	"french": "fr", "german": "de", "italian": "it", "portuguese": "pt", "dutch": "nl",
+	"swedish": "sv", "polish": "pl", "turkish": "tr", "russian": "ru", "ukrainian": "uk",
+	"arabic": "ar", "persian": "fa", "hebrew": "he", "greek": "el", "bengali": "bn",
+	"tamil": "ta", "urdu": "ur", "tagalog": "tl", "khmer": "km", "lao": "lo", "burmese": "my",
+	"georgian": "ka", "armenian": "hy", "czech": "cs", "danish": "da", "finnish": "fi",
+	"hungarian": "hu", "norwegian": "no", "romanian": "ro", "cantonese": "yue",
+}
+
+// transcriptionLanguageCode turns the language reported by a transcriber,
+// either a name such as "english" or a code, into a code.
+func transcriptionLanguageCode(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := whisperLanguages[language]; ok {
+		return code
+	}
+	if utf8.RuneCountInString(language) <= 3 {
+		return language
+	}
+	return ""
+}
@@ -0,0 +1,415 @@
+package backend
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"infinity-subtitle/backend/database"
+	"infinity-subtitle/backend/logger"
+)
+
+const (
+	// mediaDir holds uploaded media by content hash, next to the database
+	mediaDir = "media"
+	// staleUploadAge is when an unfinished upload is given up
+	staleUploadAge = 24 * time.Hour
+)
+
+// mediaUploadDir holds uploads until they are complete and hashed
+var mediaUploadDir = filepath.Join(mediaDir, "uploads")
+
+// StoredMedia identifies a file in the media directory. Files are named by
+// the SHA-256 of their content, so uploading the same file twice stores it once.
+type StoredMedia struct {
+	Hash     string `json:"hash"`
+	Size     int64  `json:"size"`
+	FileType string `json:"file_type"`
+}
+
+// mediaUpload is a file being received in chunks from the frontend. Its own
+// lock guards the writes, so that uploads do not wait on each other's disk.
+type mediaUpload struct {
+	mu       sync.Mutex
+	file     *os.File
+	hash     hash.Hash
+	size     int64
+	fileType string
+	// done is set once the upload is finished or cancelled
+	done bool
+}
+
+// mediaUploadsMu guards the map only, it is not held while writing
+var (
+	mediaUploadsMu sync.Mutex
+	mediaUploads   = make(map[string]*mediaUpload)
+)
+
+// takeMediaUpload removes an upload from the map and waits for a chunk being
+// written to it, if any.
+func takeMediaUpload(id string) (*mediaUpload, bool) {
+	mediaUploadsMu.Lock()
+	upload, ok := mediaUploads[id]
+	delete(mediaUploads, id)
+	mediaUploadsMu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+	upload.done = true
+	return upload, true
+}
+
+// unclaimedMedia holds the paths of finished uploads not yet queued or linked
+// to a movie, which count as in use until then. The lock is also held while
+// orphaned files are removed, so that an upload of the same content cannot
+// finish in between.
+var (
+	unclaimedMediaMu sync.Mutex
+	unclaimedMedia   = make(map[string]bool)
+)
+
+// claimMedia marks media files as referenced by a job or movie, no longer
+// kept by their upload alone.
+func claimMedia(paths ...string) {
+	unclaimedMediaMu.Lock()
+	defer unclaimedMediaMu.Unlock()
+	for _, path := range paths {
+		delete(unclaimedMedia, path)
+	}
+}
+
+// mediaPath is where the file with the given hash and type is stored.
+func mediaPath(hash string, fileType string) string {
+	return filepath.Join(mediaDir, hash[:2], hash+"."+fileType)
+}
+
+// mediaFileType reduces a file type to a safe, lowercase extension.
+func mediaFileType(fileType string) (string, error) {
+	fileType = strings.ToLower(strings.TrimPrefix(fileType, "."))
+	if fileType == "" || len(fileType) > 10 {
+		return "", fmt.Errorf("invalid file type %q", fileType)
+	}
+	for _, r := range fileType {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return "", fmt.Errorf("invalid file type %q", fileType)
+		}
+	}
+	return fileType, nil
+}
+
+// findMedia returns the path and size of a stored file.
+func findMedia(hash string, fileType string) (string, int64, error) {
+	fileType, err := mediaFileType(fileType)
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha256.Size*2 {
+		return "", 0, fmt.Errorf("invalid media hash %q", hash)
+	}
+
+	path := mediaPath(hash, fileType)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("media file not found, upload it again: %w", err)
+	}
+	return path, info.Size(), nil
+}
+
+// commitMedia moves a complete upload to its place in the media directory,
+// or drops it if the same content is already stored.
+func commitMedia(tmpPath string, media StoredMedia) (string, error) {
+	path := mediaPath(media.Hash, media.FileType)
+	if _, err := os.Stat(path); err == nil {
+		os.Remove(tmpPath)
+		return path, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to create media directory: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to store media: %w", err)
+	}
+	return path, nil
+}
+
+// storeMedia copies r into the media directory and returns where it went.
+func storeMedia(r io.Reader, fileType string) (StoredMedia, string, error) {
+	fileType, err := mediaFileType(fileType)
+	if err != nil {
+		return StoredMedia{}, "", err
+	}
+	if err := os.MkdirAll(mediaUploadDir, 0755); err != nil {
+		return StoredMedia{}, "", fmt.Errorf("failed to create media directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(mediaUploadDir, "upload-*")
+	if err != nil {
+		return StoredMedia{}, "", fmt.Errorf("failed to create media file: %w", err)
+	}
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, h), r)
+	file.Close()
+	if err != nil {
+		os.Remove(file.Name())
+		return StoredMedia{}, "", fmt.Errorf("failed to write media file: %w", err)
+	}
+
+	media := StoredMedia{Hash: hex.EncodeToString(h.Sum(nil)), Size: size, FileType: fileType}
+	path, err := commitMedia(file.Name(), media)
+	return media, path, err
+}
+
+// BeginMediaUpload starts receiving a file of the given type and returns the
+// upload ID to send its chunks to. Files are sent in chunks so that neither
+// side holds a whole recording in memory.
+func (mq *MovieQueue) BeginMediaUpload(fileType string) (string, error) {
+	fileType, err := mediaFileType(fileType)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(mediaUploadDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create media directory: %w", err)
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", fmt.Errorf("failed to create upload ID: %w", err)
+	}
+	id := hex.EncodeToString(idBytes)
+
+	file, err := os.Create(filepath.Join(mediaUploadDir, id))
+	if err != nil {
+		return "", fmt.Errorf("failed to create media file: %w", err)
+	}
+
+	mediaUploadsMu.Lock()
+	defer mediaUploadsMu.Unlock()
+	mediaUploads[id] = &mediaUpload{file: file, hash: sha256.New(), fileType: fileType}
+
+	return id, nil
+}
+
+// WriteMediaChunk appends a base64 encoded chunk to an upload.
+func (mq *MovieQueue) WriteMediaChunk(id string, chunk string) error {
+	data, err := base64.StdEncoding.DecodeString(chunk)
+	if err != nil {
+		return fmt.Errorf("failed to decode chunk: %w", err)
+	}
+
+	mediaUploadsMu.Lock()
+	upload, ok := mediaUploads[id]
+	mediaUploadsMu.Unlock()
+	if !ok {
+		return fmt.Errorf("upload %s not found", id)
+	}
+
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+	if upload.done {
+		return fmt.Errorf("upload %s not found", id)
+	}
+	if _, err := upload.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write media file: %w", err)
+	}
+	upload.hash.Write(data)
+	upload.size += int64(len(data))
+
+	return nil
+}
+
+// FinishMediaUpload stores a complete upload and returns what identifies it
+// in AddToQueueRequest. The file is kept until it is queued or linked to a
+// movie, even if a job with the same content is deleted meanwhile.
+func (mq *MovieQueue) FinishMediaUpload(id string) (StoredMedia, error) {
+	upload, ok := takeMediaUpload(id)
+	if !ok {
+		return StoredMedia{}, fmt.Errorf("upload %s not found", id)
+	}
+
+	if err := upload.file.Close(); err != nil {
+		os.Remove(upload.file.Name())
+		return StoredMedia{}, fmt.Errorf("failed to write media file: %w", err)
+	}
+	if upload.size == 0 {
+		os.Remove(upload.file.Name())
+		return StoredMedia{}, errors.New("uploaded file is empty")
+	}
+
+	media := StoredMedia{
+		Hash:     hex.EncodeToString(upload.hash.Sum(nil)),
+		Size:     upload.size,
+		FileType: upload.fileType,
+	}
+	unclaimedMediaMu.Lock()
+	defer unclaimedMediaMu.Unlock()
+	path, err := commitMedia(upload.file.Name(), media)
+	if err != nil {
+		return StoredMedia{}, err
+	}
+	unclaimedMedia[path] = true
+
+	return media, nil
+}
+
+// CancelMediaUpload discards an unfinished upload.
+func (mq *MovieQueue) CancelMediaUpload(id string) error {
+	upload, ok := takeMediaUpload(id)
+	if !ok {
+		return nil
+	}
+
+	upload.file.Close()
+	return os.Remove(upload.file.Name())
+}
+
+// dbExecutor is a database or a transaction.
+type dbExecutor interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// queueMediaPath returns the media file of an audio job. Jobs queued before
+// media was stored on disk keep it as base64 content, which is moved to the
+// media directory on first use.
+func queueMediaPath(db dbExecutor, mq *MovieQueue) (string, error) {
+	if mq.MediaPath != "" {
+		return mq.MediaPath, nil
+	}
+
+	var content string
+	err := db.QueryRow("SELECT content FROM movies_queue WHERE id = ?", mq.ID).Scan(&content)
+	if err != nil {
+		return "", fmt.Errorf("failed to get audio content: %w", err)
+	}
+	media, path, err := storeMedia(base64.NewDecoder(base64.StdEncoding, strings.NewReader(content)), mq.FileType)
+	if err != nil {
+		return "", fmt.Errorf("failed to store audio content: %w", err)
+	}
+
+	_, err = db.Exec("UPDATE movies_queue SET content = '', media_path = ?, media_size = ?, media_hash = ? WHERE id = ?",
+		path, media.Size, media.Hash, mq.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to update media path: %w", err)
+	}
+
+	mq.MediaPath, mq.MediaSize, mq.MediaHash = path, media.Size, media.Hash
+	return path, nil
+}
+
+// mediaInUse reports whether any job or movie still refers to a media file,
+// or it was uploaded and is about to be. Callers hold unclaimedMediaMu.
+func mediaInUse(db dbExecutor, path string) (bool, error) {
+	if unclaimedMedia[path] {
+		return true, nil
+	}
+	var count int
+	err := db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM movies_queue WHERE media_path = ?) +
+			(SELECT COUNT(*) FROM movie_media WHERE path = ?)
+	`, path, path).Scan(&count)
+	return count > 0, err
+}
+
+// removeOrphanedMedia deletes a media file once no job or movie refers to it.
+func removeOrphanedMedia(db dbExecutor, path string) error {
+	if path == "" {
+		return nil
+	}
+	unclaimedMediaMu.Lock()
+	defer unclaimedMediaMu.Unlock()
+	inUse, err := mediaInUse(db, path)
+	if err != nil {
+		return fmt.Errorf("failed to check media use: %w", err)
+	}
+	if inUse {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove media: %w", err)
+	}
+	return nil
+}
+
+// peaksInUse reports whether a file in peaksDir belongs to a stored media
+// file, or is being written.
+func peaksInUse(name string) bool {
+	hash, _, _ := strings.Cut(strings.TrimSuffix(name, ".peaks"), "-")
+	if len(hash) != sha256.Size*2 {
+		// A peaks file being written is named peaks-*
+		return true
+	}
+	matches, err := filepath.Glob(filepath.Join(mediaDir, hash[:2], hash+".*"))
+	return err != nil || len(matches) > 0
+}
+
+// CleanupMedia removes media files no job or movie refers to, such as files
+// uploaded for jobs that were never queued, their cached peaks, and uploads
+// abandoned for a day.
+func CleanupMedia() error {
+	log, err := logger.GetLogger()
+	if err != nil {
+		return fmt.Errorf("failed to get logger: %w", err)
+	}
+	db := database.GetDB()
+
+	unclaimedMediaMu.Lock()
+	defer unclaimedMediaMu.Unlock()
+
+	removed := 0
+	err = filepath.WalkDir(mediaDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		if filepath.Dir(path) == peaksDir {
+			if peaksInUse(d.Name()) {
+				return nil
+			}
+		} else if filepath.Dir(path) == mediaUploadDir {
+			info, err := d.Info()
+			if err != nil || time.Since(info.ModTime()) < staleUploadAge {
+				return nil
+			}
+		} else if inUse, err := mediaInUse(db, path); err != nil || inUse {
+			return err
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clean up media: %w", err)
+	}
+
+	if removed > 0 {
+		log.Info("Removed %d unused media files", removed)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MediaRoutePrefix is where the asset server serves the media of a movie,
+// /media/movies/<movie id>, for the preview player.
+const MediaRoutePrefix = "/media/movies/"
+
+// mediaContentTypes are sent for the media a browser can play; others go
+// as generic binary
+var mediaContentTypes = map[string]string{
+	"mp4": "video/mp4", "m4v": "video/mp4", "mov": "video/quicktime", "mkv": "video/x-matroska",
+	"mp3": "audio/mpeg", "wav": "audio/wav",
+}
+
+// mediaHandler serves movie media to the webview, with HTTP range support
+// so that players can seek.
+type mediaHandler struct{}
+
+// NewMediaHandler returns the handler for the asset server that serves movie
+// media. Only files linked to a movie or known to the queue are served,
+// never a path taken from the request.
+func NewMediaHandler() http.Handler {
+	return mediaHandler{}
+}
+
+func (h mediaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, MediaRoutePrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	movieID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, MediaRoutePrefix))
+	if err != nil || movieID <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	media, err := findMovieMedia(r.Context(), movieID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	file, err := os.Open(media.Path)
+	if err != nil {
+		http.Error(w, "media file not found", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "failed to read media file", http.StatusInternalServerError)
+		return
+	}
+
+	contentType, ok := mediaContentTypes[strings.ToLower(media.FileType)]
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	// ServeContent answers range requests and conditional requests
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+// RenderWebVTT renders the cues of a movie in one language as WebVTT, for the
+// preview player to show the subtitles as currently edited.
+func (s Subtitle) RenderWebVTT(movieID int, language string) (string, error) {
+	if language == "" {
+		return "", errors.New("language is required")
+	}
+
+	subtitles, err := getAllSubtitles(movieID)
+	if err != nil {
+		return "", err
+	}
+	cues, err := exportCues(subtitles, language, true)
+	if err != nil {
+		return "", err
+	}
+
+	var vtt strings.Builder
+	if err := writeWebVTTExport(&vtt, exportTarget{}, cues); err != nil {
+		return "", fmt.Errorf("failed to render WebVTT: %w", err)
+	}
+	return vtt.String(), nil
+}
@@ -14,8 +14,36 @@ type Movie struct {
 	Title           string            `json:"title"`
 	DefaultLanguage string            `json:"default_language"`
 	Languages       map[string]string `json:"languages"`
-	CreatedAt       time.Time         `json:"created_at"`
-	UpdatedAt       time.Time         `json:"updated_at"`
+	// PivotLanguage, if set, is translated first and used as the source for
+	// the other languages
+	PivotLanguage string `json:"pivot_language"`
+	// Metadata tells apart movies with similar titles
+	Metadata  MovieMetadata `json:"metadata"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// movieColumns is the column list read by scanMovie.
+const movieColumns = "id, title, default_language, languages, pivot_language, created_at, updated_at, " +
+	movieMetadataColumns
+
+// scanMovie reads a row selected with movieColumns.
+func scanMovie(row interface{ Scan(dest ...any) error }) (Movie, error) {
+	movie := Movie{Languages: make(map[string]string)}
+	var languages []byte
+	dest := []any{&movie.ID, &movie.Title, &movie.DefaultLanguage, &languages, &movie.PivotLanguage,
+		&movie.CreatedAt, &movie.UpdatedAt}
+	err := row.Scan(append(dest, movie.Metadata.fields()...)...)
+	if err != nil {
+		return movie, fmt.Errorf("failed to scan movie: %w", err)
+	}
+
+	err = json.Unmarshal(languages, &movie.Languages)
+	if err != nil {
+		return movie, fmt.Errorf("failed to unmarshal languages: %w", err)
+	}
+
+	return movie, nil
 }
 
 type ListMoviesResponse struct {
@@ -29,7 +57,17 @@ func NewMovie() *Movie {
 	}
 }
 
-func (m Movie) CreateMovie(title string, defaultLanguage string, languages map[string]string) (Movie, error) {
+func (m Movie) CreateMovie(title string, defaultLanguage string, languages map[string]string,
+	metadata MovieMetadata) (Movie, error) {
+	return createMovie(title, defaultLanguage, languages, "", metadata)
+}
+
+// createMovie inserts a movie with an optional pivot language, which is added
+// to the movie languages so that its translations are kept.
+func createMovie(title string, defaultLanguage string, languages map[string]string, pivotLanguage string,
+	metadata MovieMetadata) (Movie, error) {
+	var m Movie
+
 	// Input validation
 	if strings.TrimSpace(title) == "" {
 		return Movie{}, errors.New("title is required")
@@ -43,6 +81,20 @@ func (m Movie) CreateMovie(title string, defaultLanguage string, languages map[s
 		return Movie{}, errors.New("subtitle languages are required")
 	}
 
+	if pivotLanguage == defaultLanguage {
+		pivotLanguage = ""
+	}
+	if pivotLanguage != "" {
+		if _, ok := languages[pivotLanguage]; !ok {
+			return Movie{}, fmt.Errorf("pivot language %s is not a subtitle language", pivotLanguage)
+		}
+	}
+
+	metadata, err := metadata.normalize()
+	if err != nil {
+		return Movie{}, err
+	}
+
 	jsonLanguages, err := json.Marshal(languages)
 	if err != nil {
 		return Movie{}, fmt.Errorf("failed to marshal languages: %w", err)
@@ -50,16 +102,19 @@ func (m Movie) CreateMovie(title string, defaultLanguage string, languages map[s
 
 	m.Title = title
 	m.DefaultLanguage = defaultLanguage
+	m.Languages = languages
+	m.PivotLanguage = pivotLanguage
+	m.Metadata = metadata
 
 	db := database.GetDB()
 	if db == nil {
 		return Movie{}, errors.New("database connection is nil")
 	}
 
-	row, err := db.Exec("INSERT INTO movies (title, default_language, languages) VALUES (?, ?, ?)",
-		m.Title,
-		m.DefaultLanguage,
-		jsonLanguages,
+	args := []any{m.Title, m.DefaultLanguage, jsonLanguages, m.PivotLanguage}
+	row, err := db.Exec("INSERT INTO movies (title, default_language, languages, pivot_language, "+
+		movieMetadataColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		append(args, m.Metadata.values()...)...,
 	)
 
 	if err != nil {
@@ -86,26 +141,13 @@ func (m Movie) GetMovieByID(id int) (*Movie, error) {
 		return nil, errors.New("database connection is nil")
 	}
 
-	// Create a new Movie instance for each request
-	movie := &Movie{
-		Languages: make(map[string]string),
-	}
-
 	// Only select needed fields
-	row := db.QueryRow("SELECT * FROM movies WHERE id = ?", id)
-	var languages []byte
-
-	err := row.Scan(&movie.ID, &movie.Title, &movie.DefaultLanguage, &languages, &movie.CreatedAt, &movie.UpdatedAt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan movie: %w", err)
-	}
-
-	err = json.Unmarshal(languages, &movie.Languages)
+	movie, err := scanMovie(db.QueryRow("SELECT "+movieColumns+" FROM movies WHERE id = ?", id))
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal languages: %w", err)
+		return nil, err
 	}
 
-	return movie, nil
+	return &movie, nil
 }
 
 func (m Movie) UpdateMovie(movie Movie) error {
@@ -118,6 +160,21 @@ func (m Movie) UpdateMovie(movie Movie) error {
 		return errors.New("database connection is nil")
 	}
 
+	if movie.PivotLanguage == movie.DefaultLanguage {
+		movie.PivotLanguage = ""
+	}
+	if movie.PivotLanguage != "" {
+		if _, ok := movie.Languages[movie.PivotLanguage]; !ok {
+			return fmt.Errorf("pivot language %s is not a subtitle language", movie.PivotLanguage)
+		}
+	}
+
+	metadata, err := movie.Metadata.normalize()
+	if err != nil {
+		return err
+	}
+	movie.Metadata = metadata
+
 	jsonLanguages, err := json.Marshal(movie.Languages)
 	if err != nil {
 		return fmt.Errorf("failed to marshal languages: %w", err)
@@ -133,10 +190,15 @@ func (m Movie) UpdateMovie(movie Movie) error {
 		}
 	}()
 
-	_, err = tx.Exec("UPDATE movies SET title = ?, default_language = ?, languages = ? WHERE id = ?",
+	md := movie.Metadata
+	_, err = tx.Exec(`UPDATE movies SET title = ?, default_language = ?, languages = ?, pivot_language = ?,
+		year = ?, series = ?, season = ?, episode = ?, imdb_id = ?, tmdb_id = ?, runtime_minutes = ?, genre = ?, notes = ?
+		WHERE id = ?`,
 		movie.Title,
 		movie.DefaultLanguage,
 		jsonLanguages,
+		movie.PivotLanguage,
+		md.Year, md.Series, md.Season, md.Episode, md.IMDbID, md.TMDbID, md.RuntimeMinutes, md.Genre, md.Notes,
 		movie.ID)
 	if err != nil {
 		return err
@@ -152,6 +214,11 @@ func (m Movie) UpdateMovie(movie Movie) error {
 
 func (m Movie) DeleteMovie(id int) error {
 	db := database.GetDB()
+	media, err := m.ListMedia(id)
+	if err != nil {
+		return err
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -167,54 +234,57 @@ func (m Movie) DeleteMovie(id int) error {
 		return fmt.Errorf("failed to delete movie: %w", err)
 	}
 
+	_, err = tx.Exec("DELETE FROM subtitle_history WHERE subtitle_id IN (SELECT id FROM subtitles WHERE movie_id = ?)", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete subtitle history: %w", err)
+	}
+
 	_, err = tx.Exec("DELETE FROM subtitles WHERE movie_id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete subtitles: %w", err)
 	}
 
+	_, err = tx.Exec("DELETE FROM movie_media WHERE movie_id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete movie media: %w", err)
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	// Files still queued or linked to another movie are kept
+	for _, file := range media {
+		if removeErr := removeOrphanedMedia(db, file.Path); removeErr != nil {
+			return removeErr
+		}
+	}
+
 	return nil
 }
 
-func (m Movie) ListMovies(title string, pagination Pagination) (*ListMoviesResponse, error) {
+// ListMovies returns a page of the movies that match the filter, sorted by
+// any of their fields.
+func (m Movie) ListMovies(filter MovieFilter, pagination Pagination) (*ListMoviesResponse, error) {
 	db := database.GetDB()
 
-	query := "SELECT COUNT(id) FROM movies"
-	args := []any{}
-	if title != "" {
-		query += " WHERE title LIKE ?"
-		args = append(args, "%"+title+"%")
+	where, whereArgs := filter.where()
+	order, err := movieOrderBy(pagination)
+	if err != nil {
+		return nil, err
 	}
-	row := db.QueryRow(query, args...)
+
+	row := db.QueryRow("SELECT COUNT(id) FROM movies"+where, whereArgs...)
 	var rowsNumber int
-	err := row.Scan(&rowsNumber)
+	err = row.Scan(&rowsNumber)
 	if err != nil {
 		return nil, err
 	}
 	pagination.RowsNumber = rowsNumber
 
-	query = "SELECT * FROM movies"
-	args = []any{}
-
-	// Handle search by title if provided
-	if title != "" {
-		query += " WHERE title LIKE ?"
-		args = append(args, "%"+title+"%")
-	}
-
-	// Handle sorting
-	if pagination.SortBy != "" {
-		query += " ORDER BY " + pagination.SortBy
-		if pagination.Descending {
-			query += " DESC"
-		} else {
-			query += " ASC"
-		}
-	}
+	query := "SELECT " + movieColumns + " FROM movies" + where + order
+	args := append([]any{}, whereArgs...)
 
 	// Add pagination
 	offset := (pagination.Page - 1) * pagination.RowsPerPage
@@ -229,14 +299,7 @@ func (m Movie) ListMovies(title string, pagination Pagination) (*ListMoviesRespo
 
 	var movies []Movie
 	for rows.Next() {
-		var movie Movie
-		var languages []byte
-		err := rows.Scan(&movie.ID, &movie.Title, &movie.DefaultLanguage, &languages, &movie.CreatedAt, &movie.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-
-		err = json.Unmarshal(languages, &movie.Languages)
+		movie, err := scanMovie(rows)
 		if err != nil {
 			return nil, err
 		}
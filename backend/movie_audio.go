@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"infinity-subtitle/backend/database"
+)
+
+// movieAudio is the media file of a movie, which holds its audio.
+type movieAudio struct {
+	Path     string
+	Hash     string
+	FileType string
+	// StreamIndex is the audio stream of a video file, -1 for its default one
+	StreamIndex int
+}
+
+// findMovieMedia returns the primary media file linked to a movie. Movies
+// without linked media fall back to the file of their most recent audio or
+// video job.
+func findMovieMedia(ctx context.Context, movieID int) (movieAudio, error) {
+	if media, ok, err := primaryMedia(ctx, movieID); err != nil || ok {
+		return media.audio(), err
+	}
+
+	db := database.GetDB()
+
+	var media movieAudio
+	var jobType string
+	err := db.QueryRowContext(ctx, `
+		SELECT type, file_type, media_path, media_hash, stream_index FROM movies_queue
+		WHERE movie_id = ? AND media_path != ''
+		ORDER BY id DESC LIMIT 1
+	`, movieID).Scan(&jobType, &media.FileType, &media.Path, &media.Hash, &media.StreamIndex)
+	if errors.Is(err, sql.ErrNoRows) {
+		return media, errors.New("the movie has no media; link an audio or video file to it")
+	}
+	if err != nil {
+		return media, fmt.Errorf("failed to get movie media: %w", err)
+	}
+	// The stream of a video queued for its subtitles is a subtitle stream
+	if jobType != "audio" {
+		media.StreamIndex = -1
+	}
+	return media, nil
+}
+
+// findMovieAudio returns the audio of a movie: its media file, and the audio
+// stream to use if it is a video.
+func findMovieAudio(ctx context.Context, movieID int) (movieAudio, error) {
+	audio, err := findMovieMedia(ctx, movieID)
+	if err != nil {
+		return audio, err
+	}
+
+	// Without a known stream, a video is heard in its default audio stream
+	if isVideoFileType(audio.FileType) && audio.StreamIndex < 0 {
+		audio.StreamIndex, err = defaultAudioStream(ctx, audio.Path)
+		if err != nil {
+			return audio, err
+		}
+	}
+	return audio, nil
+}
+
+// wav returns the audio as a WAV file, decoding it with ffmpeg unless it is
+// WAV already. The caller calls cleanup when done with it.
+func (a movieAudio) wav(ctx context.Context) (string, func(), error) {
+	var wavPath string
+	var err error
+	switch {
+	case isVideoFileType(a.FileType):
+		wavPath, err = extractAudioStream(ctx, a.Path, a.StreamIndex)
+	case strings.EqualFold(a.FileType, "wav"):
+		return a.Path, func() {}, nil
+	default:
+		if _, err := exec.LookPath("ffmpeg"); err != nil {
+			return "", nil, fmt.Errorf("ffmpeg must be installed to decode %s audio", a.FileType)
+		}
+		wavPath, err = convertToWAV(ctx, a.Path)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return wavPath, func() { os.Remove(wavPath) }, nil
+}
+
+// movieAudioWAV returns a WAV recording of the audio of a movie, taken from
+// its media file. The caller calls cleanup when done with it.
+func movieAudioWAV(ctx context.Context, movieID int) (string, func(), error) {
+	audio, err := findMovieAudio(ctx, movieID)
+	if err != nil {
+		return "", nil, err
+	}
+	return audio.wav(ctx)
+}
@@ -0,0 +1,283 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"infinity-subtitle/backend/database"
+	"infinity-subtitle/backend/logger"
+)
+
+// MovieMedia is a media file linked to a movie, with what was probed from it
+// when it was linked.
+type MovieMedia struct {
+	ID         int    `json:"id"`
+	MovieID    int    `json:"movie_id"`
+	Path       string `json:"path"`
+	Hash       string `json:"hash"`
+	FileType   string `json:"file_type"`
+	Size       int64  `json:"size"`
+	DurationMs int64  `json:"duration_ms"`
+	// Framerate, Width and Height are zero for audio files, and when ffprobe
+	// could not tell them
+	Framerate     float64 `json:"framerate"`
+	Width         int     `json:"width"`
+	Height        int     `json:"height"`
+	AudioChannels int     `json:"audio_channels"`
+	// AudioStreamIndex is the audio stream of a video file, -1 if it has none
+	AudioStreamIndex int `json:"audio_stream_index"`
+	// Primary is the file the movie is played, synced and exported against
+	Primary   bool      `json:"primary"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// movieMediaColumns is the column list read by scanMovieMedia.
+const movieMediaColumns = `id, movie_id, path, hash, file_type, size, duration_ms, framerate, width, height,
+	audio_channels, audio_stream_index, is_primary, created_at`
+
+// scanMovieMedia reads a row selected with movieMediaColumns.
+func scanMovieMedia(row interface{ Scan(dest ...any) error }) (MovieMedia, error) {
+	var media MovieMedia
+	err := row.Scan(&media.ID, &media.MovieID, &media.Path, &media.Hash, &media.FileType, &media.Size,
+		&media.DurationMs, &media.Framerate, &media.Width, &media.Height, &media.AudioChannels,
+		&media.AudioStreamIndex, &media.Primary, &media.CreatedAt)
+	if err != nil {
+		return media, fmt.Errorf("failed to scan movie media: %w", err)
+	}
+	return media, nil
+}
+
+// Duration returns the playing time of the media, zero if it is unknown.
+func (m MovieMedia) Duration() time.Duration {
+	return time.Duration(m.DurationMs) * time.Millisecond
+}
+
+// audio returns the media as the source of the movie's audio.
+func (m MovieMedia) audio() movieAudio {
+	return movieAudio{Path: m.Path, Hash: m.Hash, FileType: m.FileType, StreamIndex: m.AudioStreamIndex}
+}
+
+// probeLinkedMedia reads the metadata of a media file: WAV natively, other
+// files with ffprobe. audioStream, if not negative, is the audio stream of a
+// video to use instead of its default one.
+func probeLinkedMedia(ctx context.Context, media MovieMedia, audioStream int) (MovieMedia, error) {
+	if strings.EqualFold(media.FileType, "wav") {
+		format, err := parseWAVFile(media.Path)
+		if err != nil {
+			return media, fmt.Errorf("failed to read WAV file: %w", err)
+		}
+		media.DurationMs = format.Duration().Milliseconds()
+		media.AudioChannels = format.Channels
+		media.AudioStreamIndex = 0
+		return media, nil
+	}
+
+	probe, err := probeMedia(ctx, media.Path)
+	if err != nil {
+		return media, err
+	}
+	media.DurationMs = probe.Duration.Milliseconds()
+	media.Framerate, media.Width, media.Height = probe.Framerate, probe.Width, probe.Height
+
+	stream, ok := probe.defaultAudio()
+	for _, s := range probe.Streams {
+		if s.Type == "audio" && s.Index == audioStream {
+			stream, ok = s, true
+		}
+	}
+	media.AudioStreamIndex = -1
+	if ok {
+		media.AudioChannels, media.AudioStreamIndex = stream.Channels, stream.Index
+	}
+	return media, nil
+}
+
+// linkMedia probes a stored media file and links it to a movie. The first
+// file linked to a movie becomes its primary one.
+func linkMedia(ctx context.Context, media MovieMedia, audioStream int) (MovieMedia, error) {
+	db := database.GetDB()
+
+	var linked bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM movie_media WHERE movie_id = ? AND path = ?)",
+		media.MovieID, media.Path).Scan(&linked)
+	if err != nil {
+		return media, fmt.Errorf("failed to check movie media: %w", err)
+	}
+	if linked {
+		return media, errors.New("the file is already linked to the movie")
+	}
+
+	media, err = probeLinkedMedia(ctx, media, audioStream)
+	if err != nil {
+		return media, err
+	}
+
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO movie_media (movie_id, path, hash, file_type, size, duration_ms, framerate, width, height,
+			audio_channels, audio_stream_index, is_primary)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+			NOT EXISTS(SELECT 1 FROM movie_media WHERE movie_id = ? AND is_primary = 1))
+	`, media.MovieID, media.Path, media.Hash, media.FileType, media.Size, media.DurationMs, media.Framerate,
+		media.Width, media.Height, media.AudioChannels, media.AudioStreamIndex, media.MovieID)
+	if err != nil {
+		return media, fmt.Errorf("failed to link media: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return media, fmt.Errorf("failed to get media ID: %w", err)
+	}
+
+	return scanMovieMedia(db.QueryRowContext(ctx, "SELECT "+movieMediaColumns+" FROM movie_media WHERE id = ?", id))
+}
+
+// linkQueuedMedia links the media file a movie was queued from. Audio jobs
+// keep the stream they transcribed; videos queued for their subtitles are
+// heard in their default audio stream.
+func linkQueuedMedia(ctx context.Context, movieID int, mq MovieQueue) error {
+	if mq.MediaPath == "" {
+		return nil
+	}
+	audioStream := -1
+	if mq.Type == "audio" {
+		audioStream = mq.StreamIndex
+	}
+	_, err := linkMedia(ctx, MovieMedia{
+		MovieID:  movieID,
+		Path:     mq.MediaPath,
+		Hash:     mq.MediaHash,
+		FileType: mq.FileType,
+		Size:     mq.MediaSize,
+	}, audioStream)
+	return err
+}
+
+// primaryMedia returns the primary media file of a movie, if it has one.
+func primaryMedia(ctx context.Context, movieID int) (MovieMedia, bool, error) {
+	db := database.GetDB()
+	row := db.QueryRowContext(ctx, "SELECT "+movieMediaColumns+" FROM movie_media WHERE movie_id = ? AND is_primary = 1",
+		movieID)
+	media, err := scanMovieMedia(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return media, false, nil
+	}
+	if err != nil {
+		return media, false, err
+	}
+	return media, true, nil
+}
+
+// LinkMedia links an uploaded media file to a movie, probing its duration,
+// framerate, resolution and audio channels. Linked media is played in the
+// preview and used for syncing, timing validation and exports.
+func (m Movie) LinkMedia(movieID int, media StoredMedia) (MovieMedia, error) {
+	if _, err := m.GetMovieByID(movieID); err != nil {
+		return MovieMedia{}, err
+	}
+	path, size, err := findMedia(media.Hash, media.FileType)
+	if err != nil {
+		return MovieMedia{}, err
+	}
+
+	linked, err := linkMedia(context.Background(), MovieMedia{
+		MovieID:  movieID,
+		Path:     path,
+		Hash:     media.Hash,
+		FileType: strings.ToLower(media.FileType),
+		Size:     size,
+	}, -1)
+	if err != nil {
+		return linked, err
+	}
+	claimMedia(path)
+	return linked, nil
+}
+
+// ListMedia returns the media files linked to a movie, the primary one first.
+func (m Movie) ListMedia(movieID int) ([]MovieMedia, error) {
+	db := database.GetDB()
+	rows, err := db.Query("SELECT "+movieMediaColumns+" FROM movie_media WHERE movie_id = ? ORDER BY is_primary DESC, id",
+		movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movie media: %w", err)
+	}
+	defer rows.Close()
+
+	media := []MovieMedia{}
+	for rows.Next() {
+		file, err := scanMovieMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get movie media: %w", err)
+	}
+	return media, nil
+}
+
+// SetPrimaryMedia makes a linked file the primary media of its movie.
+func (m Movie) SetPrimaryMedia(id int) error {
+	db := database.GetDB()
+	result, err := db.Exec(`
+		UPDATE movie_media SET is_primary = (id = ?)
+		WHERE movie_id = (SELECT movie_id FROM movie_media WHERE id = ?)
+	`, id, id)
+	if err != nil {
+		return fmt.Errorf("failed to set primary media: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("movie media %d not found", id)
+	}
+	return nil
+}
+
+// UnlinkMedia removes a file from the media of its movie, and deletes it once
+// nothing refers to it. The earliest remaining file becomes primary if the
+// primary one was removed.
+func (m Movie) UnlinkMedia(id int) error {
+	db := database.GetDB()
+
+	var movieID int
+	var path string
+	err := db.QueryRow("SELECT movie_id, path FROM movie_media WHERE id = ?", id).Scan(&movieID, &path)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("movie media %d not found", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get movie media: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM movie_media WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to unlink media: %w", err)
+	}
+	_, err = tx.Exec(`
+		UPDATE movie_media SET is_primary = 1
+		WHERE id = (SELECT MIN(id) FROM movie_media WHERE movie_id = ?)
+		AND NOT EXISTS(SELECT 1 FROM movie_media WHERE movie_id = ? AND is_primary = 1)
+	`, movieID, movieID)
+	if err != nil {
+		return fmt.Errorf("failed to set primary media: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if err := removeOrphanedMedia(db, path); err != nil {
+		log, logErr := logger.GetLogger()
+		if logErr == nil {
+			log.Error("movie media %d: %v", id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MovieMetadata tells apart movies with similar titles. Zero values are unknown.
+type MovieMetadata struct {
+	Year int `json:"year"`
+	// Series, Season and Episode are set for the episodes of a series
+	Series  string `json:"series"`
+	Season  int    `json:"season"`
+	Episode int    `json:"episode"`
+	// IMDbID is an IMDb title ID such as tt0111161
+	IMDbID         string `json:"imdb_id"`
+	TMDbID         int    `json:"tmdb_id"`
+	RuntimeMinutes int    `json:"runtime_minutes"`
+	Genre          string `json:"genre"`
+	Notes          string `json:"notes"`
+}
+
+// movieMetadataColumns are the columns of MovieMetadata, in the order of
+// its fields.
+const movieMetadataColumns = "year, series, season, episode, imdb_id, tmdb_id, runtime_minutes, genre, notes"
+
+// firstFilmYear bounds release years from below
+const firstFilmYear = 1870
+
+// imdbIDPattern finds an IMDb title ID, also inside a link to its page
+var imdbIDPattern = regexp.MustCompile(`tt\d{7,10}`)
+
+// fields returns pointers to the fields, to scan movieMetadataColumns into.
+func (md *MovieMetadata) fields() []any {
+	return []any{&md.Year, &md.Series, &md.Season, &md.Episode, &md.IMDbID, &md.TMDbID, &md.RuntimeMinutes,
+		&md.Genre, &md.Notes}
+}
+
+// values returns the fields, to write to movieMetadataColumns.
+func (md MovieMetadata) values() []any {
+	return []any{md.Year, md.Series, md.Season, md.Episode, md.IMDbID, md.TMDbID, md.RuntimeMinutes, md.Genre,
+		md.Notes}
+}
+
+// normalize trims the text fields, reduces an IMDb link to its ID and checks
+// the numbers.
+func (md MovieMetadata) normalize() (MovieMetadata, error) {
+	md.Series = strings.TrimSpace(md.Series)
+	md.Genre = strings.TrimSpace(md.Genre)
+	md.Notes = strings.TrimSpace(md.Notes)
+
+	if md.IMDbID = strings.TrimSpace(md.IMDbID); md.IMDbID != "" {
+		id := imdbIDPattern.FindString(strings.ToLower(md.IMDbID))
+		if id == "" {
+			return md, fmt.Errorf("invalid IMDb ID %q, it looks like tt0111161", md.IMDbID)
+		}
+		md.IMDbID = id
+	}
+
+	if lastYear := time.Now().Year() + 10; md.Year != 0 && (md.Year < firstFilmYear || md.Year > lastYear) {
+		return md, fmt.Errorf("year must be between %d and %d", firstFilmYear, lastYear)
+	}
+	if md.Season < 0 || md.Episode < 0 {
+		return md, errors.New("season and episode cannot be negative")
+	}
+	if md.TMDbID < 0 {
+		return md, fmt.Errorf("invalid TMDb ID %d", md.TMDbID)
+	}
+	if md.RuntimeMinutes < 0 {
+		return md, errors.New("runtime cannot be negative")
+	}
+	return md, nil
+}
+
+// episodeTag numbers an episode as S01E02, or as much of it as is known.
+func (md MovieMetadata) episodeTag() string {
+	var tag string
+	if md.Season > 0 {
+		tag += fmt.Sprintf("S%02d", md.Season)
+	}
+	if md.Episode > 0 {
+		tag += fmt.Sprintf("E%02d", md.Episode)
+	}
+	return tag
+}
+
+// MovieFilter narrows ListMovies. Empty fields match every movie.
+type MovieFilter struct {
+	// Title matches part of the title or series
+	Title  string `json:"title"`
+	Series string `json:"series"`
+	Year   int    `json:"year"`
+	Season int    `json:"season"`
+	Genre  string `json:"genre"`
+	// ExternalID is an IMDb or TMDb ID
+	ExternalID string `json:"external_id"`
+}
+
+// where returns the WHERE clause of the filter, empty if it matches every movie.
+func (f MovieFilter) where() (string, []any) {
+	var conditions []string
+	var args []any
+	if title := strings.TrimSpace(f.Title); title != "" {
+		conditions = append(conditions, "(title LIKE ? OR series LIKE ?)")
+		args = append(args, "%"+title+"%", "%"+title+"%")
+	}
+	if series := strings.TrimSpace(f.Series); series != "" {
+		conditions = append(conditions, "series LIKE ?")
+		args = append(args, "%"+series+"%")
+	}
+	if f.Year > 0 {
+		conditions = append(conditions, "year = ?")
+		args = append(args, f.Year)
+	}
+	if f.Season > 0 {
+		conditions = append(conditions, "season = ?")
+		args = append(args, f.Season)
+	}
+	if genre := strings.TrimSpace(f.Genre); genre != "" {
+		conditions = append(conditions, "genre LIKE ?")
+		args = append(args, "%"+genre+"%")
+	}
+	if id := strings.TrimSpace(f.ExternalID); id != "" {
+		if imdbID := imdbIDPattern.FindString(strings.ToLower(id)); imdbID != "" {
+			conditions = append(conditions, "imdb_id = ?")
+			args = append(args, imdbID)
+		} else if tmdbID, err := strconv.Atoi(id); err == nil {
+			conditions = append(conditions, "tmdb_id = ?")
+			args = append(args, tmdbID)
+		} else {
+			// Matches nothing, rather than every movie
+			conditions = append(conditions, "0")
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// movieSortColumns are the columns ListMovies sorts by, for each sortable
+// field. Episodes of a series sort in order.
+var movieSortColumns = map[string][]string{
+	"id":               {"id"},
+	"title":            {"title"},
+	"default_language": {"default_language"},
+	"languages":        {"languages"},
+	"created_at":       {"created_at"},
+	"updated_at":       {"updated_at"},
+	"year":             {"year"},
+	"series":           {"series", "season", "episode"},
+	"season":           {"season", "episode"},
+	"episode":          {"episode"},
+	"imdb_id":          {"imdb_id"},
+	"tmdb_id":          {"tmdb_id"},
+	"runtime_minutes":  {"runtime_minutes"},
+	"genre":            {"genre"},
+}
+
+// movieOrderBy returns the ORDER BY clause of a pagination, empty if it is
+// not sorted.
+func movieOrderBy(pagination Pagination) (string, error) {
+	if pagination.SortBy == "" {
+		return "", nil
+	}
+	columns, ok := movieSortColumns[pagination.SortBy]
+	if !ok {
+		return "", fmt.Errorf("movies cannot be sorted by %q", pagination.SortBy)
+	}
+	direction := " ASC"
+	if pagination.Descending {
+		direction = " DESC"
+	}
+	return " ORDER BY " + strings.Join(columns, direction+", ") + direction, nil
+}
+
+// fileNameReplacer replaces the characters that are not allowed in file
+// names on some systems.
+var fileNameReplacer = strings.NewReplacer("/", "-", `\`, "-", ":", " -", "*", "", "?", "", `"`, "'", "<", "",
+	">", "", "|", "-")
+
+// safeFileName makes a name usable as a file or directory name.
+func safeFileName(name string) string {
+	name = strings.Trim(strings.TrimSpace(fileNameReplacer.Replace(name)), ".")
+	if name == "" {
+		return "Untitled"
+	}
+	return name
+}
+
+// exportName names the exports of a movie: "Series - S01E02 - Title" for an
+// episode and "Title (Year)" for a movie of a known year.
+func (m Movie) exportName() string {
+	md := m.Metadata
+	if md.Series == "" && md.episodeTag() == "" {
+		if md.Year > 0 {
+			return fmt.Sprintf("%s (%d)", m.Title, md.Year)
+		}
+		return m.Title
+	}
+
+	var parts []string
+	if md.Series != "" {
+		parts = append(parts, md.Series)
+	}
+	if tag := md.episodeTag(); tag != "" {
+		parts = append(parts, tag)
+	}
+	if !strings.EqualFold(m.Title, md.Series) {
+		parts = append(parts, m.Title)
+	}
+	return strings.Join(parts, " - ")
+}
+
+// exportDir is the directory of a movie's exports under the subtitles
+// directory: episodes are grouped by series and season.
+func (m Movie) exportDir() string {
+	md := m.Metadata
+	if md.Series == "" {
+		return safeFileName(m.exportName())
+	}
+	if md.Season > 0 {
+		return filepath.Join(safeFileName(md.Series), fmt.Sprintf("Season %02d", md.Season))
+	}
+	return safeFileName(md.Series)
+}
@@ -3,12 +3,12 @@ package backend
 import (
 	"context"
 	"database/sql"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"infinity-subtitle/backend/database"
 	"infinity-subtitle/backend/logger"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -23,9 +23,25 @@ type MovieQueue struct {
 	Content         string            `json:"content"`
 	SourceLanguage  string            `json:"source_language"`
 	TargetLanguages map[string]string `json:"target_languages"`
+	PivotLanguage   string            `json:"pivot_language"`
 	Status          int               `json:"status"`
 	CreatedAt       time.Time         `json:"created_at"`
 	UpdatedAt       *time.Time        `json:"updated_at"`
+	// Transcriber transcribes an audio job, empty for the one chosen in the settings
+	Transcriber string `json:"transcriber"`
+	// MediaPath is the stored media file of an audio job, see StoredMedia
+	MediaPath string `json:"media_path"`
+	MediaSize int64  `json:"media_size"`
+	MediaHash string `json:"media_hash"`
+	// StreamIndex is the audio stream transcribed from a video file, -1 for other files
+	StreamIndex int `json:"stream_index"`
+	// DetectedLanguage is the language found in the upload, empty if it could not be told
+	DetectedLanguage string `json:"detected_language"`
+	// TranslateAudio also makes the English subtitles of an audio job from
+	// its audio, with the translations endpoint, rather than from the transcript
+	TranslateAudio bool `json:"translate_audio"`
+	// TranslationErrors holds the languages left incomplete by the last translation run
+	TranslationErrors []TranslationReport `json:"translation_errors"`
 }
 
 type MovieQueueResponse struct {
@@ -40,6 +56,17 @@ type AddToQueueRequest struct {
 	Content         string   `json:"content"`
 	SourceLanguage  string   `json:"source_language"`
 	TargetLanguages []string `json:"target_languages"`
+	// PivotLanguage is optional, see Movie.PivotLanguage
+	PivotLanguage string `json:"pivot_language"`
+	// Transcriber is optional, see MovieQueue.Transcriber
+	Transcriber string `json:"transcriber"`
+	// MediaHash is the uploaded file of an audio job, see FinishMediaUpload.
+	// Subtitles may also come from an uploaded video file.
+	MediaHash string `json:"media_hash"`
+	// StreamIndex picks the stream of a video file, see ProbeMedia
+	StreamIndex int `json:"stream_index"`
+	// TranslateAudio is optional, see MovieQueue.TranslateAudio
+	TranslateAudio bool `json:"translate_audio"`
 }
 
 const (
@@ -49,6 +76,8 @@ const (
 	MovieQueueStatusSubtitleCreated
 	MovieQueueStatusSubtitleTranslated
 	MovieQueueStatusFailed
+	MovieQueueStatusTranslationIncomplete
+	MovieQueueStatusTranslationCancelled
 )
 
 func NewMovieQueue() *MovieQueue {
@@ -77,8 +106,8 @@ func (mq *MovieQueue) ListQueue(name string, pagination Pagination) (MovieQueueR
 
 	offset := (pagination.Page - 1) * pagination.RowsPerPage
 
-	query = "SELECT id, movie_id, name, type, file_type, source_language, target_languages, status," +
-		"created_at, updated_at FROM movies_queue"
+	query = "SELECT id, movie_id, name, type, file_type, source_language, target_languages, pivot_language, transcriber," +
+		"media_size, detected_language, translate_audio, status, created_at, updated_at, translation_errors FROM movies_queue"
 	if name != "" {
 		query += " WHERE name LIKE ?"
 		args = append(args, "%"+name+"%")
@@ -108,6 +137,7 @@ func (mq *MovieQueue) ListQueue(name string, pagination Pagination) (MovieQueueR
 		var movie MovieQueue
 		var updatedAt sql.NullTime
 		var targetLanguagesJSON []byte
+		var translationErrorsJSON []byte
 		err := rows.Scan(
 			&movie.ID,
 			&movie.MovieID,
@@ -116,9 +146,15 @@ func (mq *MovieQueue) ListQueue(name string, pagination Pagination) (MovieQueueR
 			&movie.FileType,
 			&movie.SourceLanguage,
 			&targetLanguagesJSON,
+			&movie.PivotLanguage,
+			&movie.Transcriber,
+			&movie.MediaSize,
+			&movie.DetectedLanguage,
+			&movie.TranslateAudio,
 			&movie.Status,
 			&movie.CreatedAt,
 			&updatedAt,
+			&translationErrorsJSON,
 		)
 		if err != nil {
 			return response, fmt.Errorf("failed to scan movie: %w", err)
@@ -130,6 +166,12 @@ func (mq *MovieQueue) ListQueue(name string, pagination Pagination) (MovieQueueR
 		if err != nil {
 			return response, fmt.Errorf("failed to unmarshal target languages: %w", err)
 		}
+		if len(translationErrorsJSON) > 0 {
+			err = json.Unmarshal(translationErrorsJSON, &movie.TranslationErrors)
+			if err != nil {
+				return response, fmt.Errorf("failed to unmarshal translation errors: %w", err)
+			}
+		}
 
 		movies = append(movies, movie)
 	}
@@ -162,15 +204,81 @@ func (mq *MovieQueue) AddToQueue(req []AddToQueueRequest) error {
 
 	stmt, err := db.Prepare(`
 		INSERT INTO movies_queue (
-			name, type, file_type, content, source_language, target_languages, status, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+			name, type, file_type, content, source_language, target_languages, pivot_language, transcriber,
+			media_path, media_size, media_hash, stream_index, detected_language, translate_audio, status, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
+	var queuedMedia []string
 	for _, r := range req {
+		// Subtitles are read now; audio is detected when it is transcribed
+		detected := ""
+		var mediaPath string
+		var mediaSize int64
+		streamIndex := -1
+		if r.Type == "audio" || isVideoFileType(r.FileType) {
+			mediaPath, mediaSize, err = findMedia(r.MediaHash, r.FileType)
+			if err != nil {
+				return fmt.Errorf("%s: %w", r.Name, err)
+			}
+			queuedMedia = append(queuedMedia, mediaPath)
+		}
+		if isVideoFileType(r.FileType) {
+			streamIndex = r.StreamIndex
+		}
+
+		if r.Type == "audio" {
+			if isVideoFileType(r.FileType) {
+				if _, err := findStream(context.Background(), mediaPath, r.StreamIndex, "audio"); err != nil {
+					return fmt.Errorf("%s: %w", r.Name, err)
+				}
+			}
+			r.Content = ""
+		} else {
+			if isVideoFileType(r.FileType) {
+				// The subtitle stream is imported as is; the video stays for reference
+				r.Content, err = extractQueuedSubtitles(r)
+				if err != nil {
+					return fmt.Errorf("%s: %w", r.Name, err)
+				}
+			}
+			detected, _ = detectSubtitleLanguage(r.Content)
+			if r.SourceLanguage == AutoLanguage {
+				if _, ok := langMap[detected]; !ok {
+					return fmt.Errorf("%s: could not detect a supported source language", r.Name)
+				}
+				r.SourceLanguage = detected
+			}
+		}
+
+		if r.PivotLanguage != "" && r.PivotLanguage == r.SourceLanguage {
+			return fmt.Errorf("%s: pivot language cannot be the source language", r.Name)
+		}
+		if err := validateTranscriber(r.Transcriber); err != nil {
+			return fmt.Errorf("%s: %w", r.Name, err)
+		}
+		if r.TranslateAudio {
+			if r.Type != "audio" {
+				return fmt.Errorf("%s: only audio can be translated from the audio", r.Name)
+			}
+			if r.SourceLanguage == audioTranslationLanguage {
+				return fmt.Errorf("%s: the audio is already in English", r.Name)
+			}
+			if !transcriberCanTranslate(r.Transcriber) {
+				return fmt.Errorf("%s: the transcriber cannot translate audio", r.Name)
+			}
+			if _, ok := langMap[audioTranslationLanguage]; !ok {
+				return fmt.Errorf("%s: English must be added to the languages to translate audio", r.Name)
+			}
+			if !slices.Contains(r.TargetLanguages, audioTranslationLanguage) {
+				r.TargetLanguages = append(r.TargetLanguages, audioTranslationLanguage)
+			}
+		}
+
 		targetLanguages := make(map[string]string)
 		var targetLanguagesJSON []byte
 
@@ -184,23 +292,98 @@ func (mq *MovieQueue) AddToQueue(req []AddToQueueRequest) error {
 		}
 
 		// Always set initial status to pending
-		_, err = stmt.Exec(r.Name, r.Type, r.FileType, r.Content, r.SourceLanguage, targetLanguagesJSON, MovieQueueStatusPending)
+		_, err = stmt.Exec(r.Name, r.Type, r.FileType, r.Content, r.SourceLanguage, targetLanguagesJSON,
+			r.PivotLanguage, r.Transcriber, mediaPath, mediaSize, r.MediaHash, streamIndex, detected, r.TranslateAudio,
+			MovieQueueStatusPending)
 		if err != nil {
 			return fmt.Errorf("failed to add movie to queue: %w", err)
 		}
 	}
 
+	claimMedia(queuedMedia...)
 	return nil
 }
 
+// DetectLanguages detects the languages of subtitle uploads before they are
+// queued, flagging those that contradict the declared source language. Audio
+// is skipped, its language is only known once transcribed.
+func (mq *MovieQueue) DetectLanguages(req []AddToQueueRequest) ([]LanguageDetection, error) {
+	detections := make([]LanguageDetection, 0, len(req))
+	for _, r := range req {
+		if r.Type == "audio" {
+			continue
+		}
+		detected, confidence := detectSubtitleLanguage(r.Content)
+		detections = append(detections, LanguageDetection{
+			Name:       r.Name,
+			Declared:   r.SourceLanguage,
+			Detected:   detected,
+			Confidence: confidence,
+			Mismatch:   languageMismatch(r.SourceLanguage, detected, confidence),
+		})
+	}
+	return detections, nil
+}
+
 func (mq *MovieQueue) DeleteFromQueue(id int) error {
 	db := database.GetDB()
 
-	_, err := db.Exec("DELETE FROM movies_queue WHERE id = ?", id)
+	var mediaPath string
+	err := db.QueryRow("SELECT media_path FROM movies_queue WHERE id = ?", id).Scan(&mediaPath)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get movie from queue: %w", err)
+	}
+
+	_, err = db.Exec("DELETE FROM movies_queue WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete movie from queue: %w", err)
 	}
 
+	return removeOrphanedMedia(db.DB, mediaPath)
+}
+
+// AcceptTranslationGaps marks a job with untranslated subtitles as translated,
+// keeping the recorded errors for reference.
+func (mq *MovieQueue) AcceptTranslationGaps(id int) error {
+	db := database.GetDB()
+
+	result, err := db.Exec("UPDATE movies_queue SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND status = ?",
+		MovieQueueStatusSubtitleTranslated, id, MovieQueueStatusTranslationIncomplete)
+	if err != nil {
+		return fmt.Errorf("failed to accept translation gaps: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to accept translation gaps: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("queue id %d has no translation gaps to accept", id)
+	}
+
+	return nil
+}
+
+// RetryTranslation sends a job with untranslated subtitles, or one that was
+// cancelled, back to the translation worker, which only fills subtitles that
+// are still empty.
+func (mq *MovieQueue) RetryTranslation(id int) error {
+	db := database.GetDB()
+
+	result, err := db.Exec("UPDATE movies_queue SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND status IN (?, ?)",
+		MovieQueueStatusSubtitleCreated, id, MovieQueueStatusTranslationIncomplete, MovieQueueStatusTranslationCancelled)
+	if err != nil {
+		return fmt.Errorf("failed to retry translation: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to retry translation: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("queue id %d has no translation gaps to retry", id)
+	}
+
 	return nil
 }
 
@@ -228,10 +411,9 @@ func CreateMovieFromQueue(ctx context.Context) error {
 			langMap[lang.Code] = lang.Name
 		}
 
-		m := NewMovie()
-
 		rows, err := db.QueryContext(ctx, `
-		SELECT id, name, type, file_type, content, source_language, target_languages, status
+		SELECT id, name, type, file_type, content, source_language, target_languages, pivot_language, status,
+		  media_path, media_hash, media_size, stream_index
 		FROM movies_queue 
 		WHERE movie_id IS NULL
 		AND (
@@ -249,7 +431,8 @@ func CreateMovieFromQueue(ctx context.Context) error {
 		for rows.Next() {
 			var mq MovieQueue
 			var targetLanguagesJSON []byte
-			err := rows.Scan(&mq.ID, &mq.Name, &mq.Type, &mq.FileType, &mq.Content, &mq.SourceLanguage, &targetLanguagesJSON, &mq.Status)
+			err := rows.Scan(&mq.ID, &mq.Name, &mq.Type, &mq.FileType, &mq.Content, &mq.SourceLanguage, &targetLanguagesJSON,
+				&mq.PivotLanguage, &mq.Status, &mq.MediaPath, &mq.MediaHash, &mq.MediaSize, &mq.StreamIndex)
 			if err != nil {
 				return fmt.Errorf("failed to scan movie from queue: %w", err)
 			}
@@ -271,8 +454,11 @@ func CreateMovieFromQueue(ctx context.Context) error {
 			}
 
 			mq.TargetLanguages[mq.SourceLanguage] = langMap[mq.SourceLanguage]
+			if mq.PivotLanguage != "" {
+				mq.TargetLanguages[mq.PivotLanguage] = langMap[mq.PivotLanguage]
+			}
 
-			m, err := m.CreateMovie(mq.Name, mq.SourceLanguage, mq.TargetLanguages)
+			m, err := createMovie(mq.Name, mq.SourceLanguage, mq.TargetLanguages, mq.PivotLanguage, MovieMetadata{})
 			if err != nil {
 				if rollbackErr := tx.Rollback(); rollbackErr != nil {
 					logger.Error("failed to rollback transaction: %w", rollbackErr)
@@ -281,6 +467,14 @@ func CreateMovieFromQueue(ctx context.Context) error {
 			}
 			logger.Info("movie created from queue id: %d", mq.ID)
 
+			if err := assignQueueUsageToMovie(mq.ID, m.ID); err != nil {
+				logger.Error("queue id %d: %v", mq.ID, err)
+			}
+			// The movie still finds its media through the queue if probing fails
+			if err := linkQueuedMedia(ctx, m.ID, mq); err != nil {
+				logger.Error("queue id %d: failed to link media: %v", mq.ID, err)
+			}
+
 			_, err = tx.ExecContext(ctx,
 				"UPDATE movies_queue SET movie_id = ?, status = ? WHERE id = ?",
 				m.ID, MovieQueueStatusMovieCreated, mq.ID)
@@ -321,7 +515,8 @@ func CreateSubtitleFromQueue(ctx context.Context) error {
 		s := NewSubtitle()
 
 		rows, err := db.QueryContext(ctx, `
-		SELECT mq.id as mid, mq.movie_id, mq.name, mq.content, mq.source_language, mq.target_languages, mq.status, 
+		SELECT mq.id as mid, mq.movie_id, mq.name, mq.content, mq.english_content, mq.source_language,
+		  mq.target_languages, mq.status, 
 		  mq.created_at as mq_created_at, mq.updated_at as mq_updated_at,
 		  m.id, m.title, m.default_language, m.languages, m.created_at, m.updated_at
 		FROM movies_queue mq
@@ -336,9 +531,10 @@ func CreateSubtitleFromQueue(ctx context.Context) error {
 		defer rows.Close()
 
 		type MovieWithContent struct {
-			Movie   Movie
-			MQ      MovieQueue
-			Content string
+			Movie          Movie
+			MQ             MovieQueue
+			Content        string
+			EnglishContent string
 		}
 
 		var moviesWithContent []MovieWithContent
@@ -348,7 +544,8 @@ func CreateSubtitleFromQueue(ctx context.Context) error {
 			var updatedAt sql.NullTime
 			var targetLanguagesJSON []byte
 			var jsonLanguages []byte
-			err := rows.Scan(&mwc.MQ.ID, &mwc.MQ.MovieID, &mwc.MQ.Name, &mwc.MQ.Content, &mwc.MQ.SourceLanguage,
+			err := rows.Scan(&mwc.MQ.ID, &mwc.MQ.MovieID, &mwc.MQ.Name, &mwc.MQ.Content, &mwc.EnglishContent,
+				&mwc.MQ.SourceLanguage,
 				&targetLanguagesJSON, &mwc.MQ.Status, &mwc.MQ.CreatedAt, &mwc.MQ.UpdatedAt,
 				&mwc.Movie.ID, &mwc.Movie.Title, &mwc.Movie.DefaultLanguage, &jsonLanguages,
 				&mwc.Movie.CreatedAt, &mwc.Movie.UpdatedAt)
@@ -387,6 +584,18 @@ func CreateSubtitleFromQueue(ctx context.Context) error {
 				return fmt.Errorf("failed to create subtitle from queue id: %d: %w", mwc.MQ.ID, err)
 			}
 
+			// The English track from the audio takes the transcript's timing;
+			// cues it leaves empty are translated from the transcript later
+			if mwc.EnglishContent != "" {
+				err = importAlignedTrack(mwc.Movie.ID, audioTranslationLanguage, mwc.EnglishContent)
+				if err != nil {
+					if rollbackErr := tx.Rollback(); rollbackErr != nil {
+						logger.Error("failed to rollback transaction: %w", rollbackErr)
+					}
+					return fmt.Errorf("failed to import English track from queue id: %d: %w", mwc.MQ.ID, err)
+				}
+			}
+
 			logger.Info("subtitle created from queue %d", mwc.MQ.ID)
 			_, err = tx.ExecContext(ctx, "UPDATE movies_queue SET status = ? WHERE movie_id = ?",
 				MovieQueueStatusSubtitleCreated, mwc.Movie.ID)
@@ -461,35 +670,92 @@ func TranslateSubtitleFromQueue(ctx context.Context) error {
 			return fmt.Errorf("failed to get movies from queue: %w", err)
 		}
 
-		s := NewSubtitle()
-
 		for _, movie := range movies {
 			tx, err := db.BeginTx(ctx, nil)
 			if err != nil {
 				return fmt.Errorf("failed to begin transaction: %w", err)
 			}
 
+			qualitySettings := getQualitySettings()
+			incomplete := make([]TranslationReport, 0)
+			job, jobCtx := startJob(ctx, JobKindQueueTranslation, movie.m.ID, movie.MqId)
+			cancelled := false
 			for code := range movie.m.Languages {
 				if code == movie.m.DefaultLanguage {
 					continue
 				}
 
-				_, err := s.TranslateSubtitles(movie.m.ID, movie.m.DefaultLanguage, code)
+				report, err := translateSubtitles(jobCtx, movie.MqId, movie.m.ID, movie.m.DefaultLanguage, code,
+					translationSelection{})
 				if err != nil {
+					job.finish()
 					if rollbackErr := tx.Rollback(); rollbackErr != nil {
 						logger.Error("failed to rollback transaction: %w", rollbackErr)
 					}
 					return fmt.Errorf("failed to translate subtitles: %w", err)
 				}
+				if report.Cancelled {
+					incomplete = append(incomplete, report)
+					cancelled = true
+					break
+				}
+				if !report.Complete() {
+					logger.Warn("queue id %d: %d subtitles left untranslated in %s",
+						movie.MqId, len(report.UntranslatedIDs), code)
+					incomplete = append(incomplete, report)
+				}
+
+				if qualitySettings.Method != "" {
+					qualityReport, err := checkTranslationQuality(jobCtx, movie.MqId, movie.m.ID,
+						movie.m.DefaultLanguage, code, qualitySettings.Method)
+					if jobCtx.Err() != nil {
+						// Cancelled during the quality check, which is left unfinished
+						cancelled = true
+						break
+					}
+					if err != nil {
+						logger.Error("queue id %d: quality check of %s failed: %v", movie.MqId, code, err)
+					} else {
+						logger.Info("queue id %d: %d of %d %s subtitles flagged for review",
+							movie.MqId, len(qualityReport.FlaggedIDs), qualityReport.Checked, code)
+					}
+				}
 			}
+			job.finish()
 
-			_, err = tx.ExecContext(ctx, "UPDATE movies_queue SET status = ? WHERE movie_id = ?",
-				MovieQueueStatusSubtitleTranslated, movie.m.ID)
+			if cancelled && !job.wasCancelled() {
+				// The app is shutting down, the job is picked up again on the next start
+				if rollbackErr := tx.Rollback(); rollbackErr != nil {
+					logger.Error("failed to rollback transaction: %w", rollbackErr)
+				}
+				return nil
+			}
+
+			status := MovieQueueStatusSubtitleTranslated
+			var translationErrorsJSON []byte
+			if cancelled {
+				status = MovieQueueStatusTranslationCancelled
+			} else if len(incomplete) > 0 {
+				status = MovieQueueStatusTranslationIncomplete
+			}
+			if len(incomplete) > 0 {
+				translationErrorsJSON, err = json.Marshal(incomplete)
+				if err != nil {
+					if rollbackErr := tx.Rollback(); rollbackErr != nil {
+						logger.Error("failed to rollback transaction: %w", rollbackErr)
+					}
+					return fmt.Errorf("failed to marshal translation errors: %w", err)
+				}
+			}
+
+			_, err = tx.ExecContext(ctx,
+				"UPDATE movies_queue SET status = ?, translation_errors = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+				status, translationErrorsJSON, movie.MqId)
 			if err != nil {
 				if rollbackErr := tx.Rollback(); rollbackErr != nil {
 					logger.Error("failed to rollback transaction: %w", rollbackErr)
 				}
-				return fmt.Errorf("failed to update status of movie queue id: %d: %w", movie.m.ID, err)
+				return fmt.Errorf("failed to update status of movie queue id: %d: %w", movie.MqId, err)
 			}
 
 			if err = tx.Commit(); err != nil {
@@ -499,6 +765,18 @@ func TranslateSubtitleFromQueue(ctx context.Context) error {
 				return fmt.Errorf("failed to commit transaction: %w", err)
 			}
 
+			if status == MovieQueueStatusTranslationCancelled {
+				logger.Info("subtitle queue id translation cancelled: %d", movie.MqId)
+				runtime.EventsEmit(ctx, "subtitle-translation-cancelled", movie.MqId, status, incomplete)
+				continue
+			}
+
+			if status == MovieQueueStatusTranslationIncomplete {
+				logger.Info("subtitle queue id translation incomplete: %d", movie.MqId)
+				runtime.EventsEmit(ctx, "subtitle-translation-incomplete", movie.MqId, status, incomplete)
+				continue
+			}
+
 			logger.Info("subtitle queue id status translated: %d", movie.MqId)
 			runtime.EventsEmit(ctx, "subtitle-translated", movie.MqId, MovieQueueStatusSubtitleTranslated)
 		}
@@ -519,24 +797,21 @@ func TranscribeAudioFromQueue(ctx context.Context) error {
 	default:
 		db := database.GetDB()
 
-		// Use a transaction for the entire query to prevent locks
+		// Only this worker takes pending audio jobs. The transaction is
+		// committed before transcribing, as its lock would keep the usage of
+		// the transcription from being recorded
 		tx, err := db.BeginTx(ctx, &sql.TxOptions{
 			Isolation: sql.LevelSerializable,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to begin transaction: %w", err)
 		}
-		defer func() {
-			if err != nil {
-				if rollbackErr := tx.Rollback(); rollbackErr != nil {
-					logger.Error("failed to rollback transaction: %w", rollbackErr)
-				}
-			}
-		}()
+		defer tx.Rollback()
 
 		// First, try to get and lock a single row
 		rows, err := tx.QueryContext(ctx, `
-		SELECT id, name, file_type, content, source_language, target_languages, status
+		SELECT id, name, file_type, media_path, stream_index, source_language, target_languages, transcriber,
+		  translate_audio, status
 		FROM movies_queue 
 		WHERE status = ? AND type = 'audio'
 		LIMIT 1
@@ -553,7 +828,8 @@ func TranscribeAudioFromQueue(ctx context.Context) error {
 		// Only process one file at a time
 		if rows.Next() {
 			found = true
-			err := rows.Scan(&mq.ID, &mq.Name, &mq.FileType, &mq.Content, &mq.SourceLanguage, &targetLanguagesJSON, &mq.Status)
+			err := rows.Scan(&mq.ID, &mq.Name, &mq.FileType, &mq.MediaPath, &mq.StreamIndex, &mq.SourceLanguage,
+				&targetLanguagesJSON, &mq.Transcriber, &mq.TranslateAudio, &mq.Status)
 			if err != nil {
 				return fmt.Errorf("failed to scan audio file from queue: %w", err)
 			}
@@ -576,46 +852,79 @@ func TranscribeAudioFromQueue(ctx context.Context) error {
 			return nil
 		}
 
-		// Decode base64 content
-		audioData, err := base64.StdEncoding.DecodeString(mq.Content)
+		// The stored file is sent as is, never loaded into memory
+		audioPath, err := queueMediaPath(tx, &mq)
 		if err != nil {
-			return fmt.Errorf("failed to decode base64 audio content: %w", err)
+			return err
 		}
-
-		// Create a temporary file for the audio
-		tmpFile, err := os.CreateTemp("", "audio-*."+mq.FileType)
-		if err != nil {
-			return fmt.Errorf("failed to create temporary file: %w", err)
+		rows.Close()
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
 		}
-		defer os.Remove(tmpFile.Name())
-
-		// Write audio data to temporary file
-		if _, err := tmpFile.Write(audioData); err != nil {
-			return fmt.Errorf("failed to write audio data to temporary file: %w", err)
+		if isVideoFileType(mq.FileType) {
+			audioPath, err = extractAudioStream(ctx, audioPath, mq.StreamIndex)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(audioPath)
 		}
-		tmpFile.Close()
 
 		// Call transcription service
-		srtContent, err := transcribeAudio(tmpFile.Name(), mq.SourceLanguage)
+		transcriber, err := newTranscriber(mq.Transcriber, usageKey{QueueID: mq.ID, Language: mq.SourceLanguage})
+		if err != nil {
+			return fmt.Errorf("failed to create transcriber: %w", err)
+		}
+		srtContent, detected, err := transcribeAudio(ctx, transcriber, audioPath, mq.SourceLanguage)
 		if err != nil {
 			return fmt.Errorf("failed to transcribe audio: %w", err)
 		}
 
-		// Update queue status and content with transcribed text
-		_, err = tx.ExecContext(ctx,
-			"UPDATE movies_queue SET content = ?, status = ? WHERE id = ?",
-			srtContent, MovieQueueStatusAudioTranscribed, mq.ID)
-		if err != nil {
-			return fmt.Errorf("failed to update audio transcription status: %w", err)
+		status := MovieQueueStatusAudioTranscribed
+		if mq.SourceLanguage == AutoLanguage {
+			var langs []Language
+			langs, err = NewLanguage().GetAllLanguages()
+			if err != nil {
+				return fmt.Errorf("failed to get languages: %w", err)
+			}
+			supported := false
+			for _, lang := range langs {
+				supported = supported || lang.Code == detected
+			}
+			if supported {
+				mq.SourceLanguage = detected
+			} else {
+				// Nothing can be made of a transcript in an unknown language
+				logger.Error("queue id %d: detected language %q is not supported", mq.ID, detected)
+				status = MovieQueueStatusFailed
+			}
+		} else if languageMismatch(mq.SourceLanguage, detected, 1) {
+			// With a declared language, detected is only set when the transcript was clear
+			logger.Warn("queue id %d: declared language %s but the audio is in %s", mq.ID, mq.SourceLanguage, detected)
+			runtime.EventsEmit(ctx, "language-mismatch", mq.ID, mq.SourceLanguage, detected)
 		}
 
-		// Commit the transaction
-		if err = tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit transaction: %w", err)
+		// English audio needs no translation, its transcript is the English track
+		englishContent := ""
+		if mq.TranslateAudio && status != MovieQueueStatusFailed && mq.SourceLanguage != audioTranslationLanguage {
+			englishContent, err = translateAudioToSRT(ctx, mq.Transcriber,
+				usageKey{QueueID: mq.ID, Language: audioTranslationLanguage}, audioPath)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Update queue status and content with transcribed text, unless the job
+		// was deleted or changed while it was transcribed
+		_, err = db.ExecContext(ctx, `
+			UPDATE movies_queue SET content = ?, english_content = ?, source_language = ?, detected_language = ?, status = ?
+			WHERE id = ? AND status = ?
+		`, srtContent, englishContent, mq.SourceLanguage, detected, status, mq.ID, MovieQueueStatusPending)
+		if err != nil {
+			return fmt.Errorf("failed to update audio transcription status: %w", err)
 		}
 
 		logger.Info("audio transcription completed for queue id: %d", mq.ID)
-		runtime.EventsEmit(ctx, "audio-transcribed", mq.ID, MovieQueueStatusAudioTranscribed)
+		runtime.EventsEmit(ctx, "audio-transcribed", mq.ID, status, mq.SourceLanguage, detected)
 		return nil
 	}
 }
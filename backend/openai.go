@@ -0,0 +1,223 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"infinity-subtitle/backend/logger"
+
+	_ "github.com/joho/godotenv/autoload"
+	"github.com/sashabaranov/go-openai"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultRequestsPerMinute = 500
+	defaultTokensPerMinute   = 200000
+	defaultMaxRetries        = 5
+
+	retryBaseDelay = time.Second
+	retryMaxDelay  = time.Minute
+)
+
+// RetryPolicy controls how failed OpenAI requests are retried.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// backoff returns the delay before the given retry attempt, starting at 0.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	// Full jitter over the upper half keeps parallel workers from retrying in lockstep
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryingDoer is an openai.HTTPDoer that retries rate limited and transient
+// server errors, honoring the Retry-After headers sent by the API and the
+// shared request limit.
+type retryingDoer struct {
+	client *http.Client
+	policy RetryPolicy
+	logger *logger.Logger
+}
+
+func (d *retryingDoer) Do(req *http.Request) (*http.Response, error) {
+	canRewind := req.Body == nil || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			req.Body = body
+		}
+
+		resp, err := d.client.Do(req)
+
+		retryable, wait := d.classify(resp, err)
+		if !retryable || !canRewind || attempt >= d.policy.MaxRetries {
+			return resp, err
+		}
+
+		if wait == 0 {
+			wait = d.policy.backoff(attempt)
+		}
+
+		if err != nil {
+			d.logger.Warn("OpenAI request to %s failed, retrying in %s: %v", req.URL.Path, wait, err)
+		} else {
+			d.logger.Warn("OpenAI request to %s returned %s, retrying in %s", req.URL.Path, resp.Status, wait)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+
+		// Retries count against the request limit like first attempts, so
+		// that parallel workers retrying at once cannot exceed it
+		if err := getAPILimiter().Wait(req.Context(), 0); err != nil {
+			return nil, fmt.Errorf("rate limit exceeded: %w", err)
+		}
+	}
+}
+
+// classify reports whether a response should be retried and how long the
+// server asked us to wait, if it did.
+func (d *retryingDoer) classify(resp *http.Response, err error) (bool, time.Duration) {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded), 0
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		// An exhausted quota is reported as 429 too, but waiting won't fix it
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if readErr != nil || bytes.Contains(body, []byte("insufficient_quota")) {
+			return false, 0
+		}
+		return true, retryAfter(resp.Header)
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, retryAfter(resp.Header)
+	}
+
+	return false, 0
+}
+
+// retryAfter parses the retry-after-ms and Retry-After headers.
+func retryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
+
+// apiLimiter throttles OpenAI calls by requests and tokens per minute. It is
+// shared by every service so that parallel jobs respect the same quota.
+type apiLimiter struct {
+	requests *rate.Limiter
+	tokens   *rate.Limiter
+}
+
+var (
+	sharedLimiter   *apiLimiter
+	sharedLimiterMu sync.Mutex
+)
+
+func getAPILimiter() *apiLimiter {
+	sharedLimiterMu.Lock()
+	defer sharedLimiterMu.Unlock()
+
+	if sharedLimiter == nil {
+		sharedLimiter = newAPILimiter(getAPILimits())
+	}
+	return sharedLimiter
+}
+
+// resetAPILimiter makes the next call pick up changed settings.
+func resetAPILimiter() {
+	sharedLimiterMu.Lock()
+	defer sharedLimiterMu.Unlock()
+
+	sharedLimiter = nil
+}
+
+func newAPILimiter(limits APILimits) *apiLimiter {
+	return &apiLimiter{
+		requests: rate.NewLimiter(rate.Limit(float64(limits.RequestsPerMinute)/60), max(limits.RequestsPerMinute/60, 1)),
+		tokens:   rate.NewLimiter(rate.Limit(float64(limits.TokensPerMinute)/60), limits.TokensPerMinute),
+	}
+}
+
+// Wait blocks until a request using about the given number of tokens may be sent.
+func (l *apiLimiter) Wait(ctx context.Context, tokens int) error {
+	if err := l.requests.Wait(ctx); err != nil {
+		return err
+	}
+
+	if tokens <= 0 {
+		return nil
+	}
+	return l.tokens.WaitN(ctx, min(tokens, l.tokens.Burst()))
+}
+
+// estimateTokens gives a rough token count for text, erring on the high side
+// for non-latin scripts.
+func estimateTokens(text string) int {
+	return utf8.RuneCountInString(text)/3 + 1
+}
+
+// newOpenAIClient returns a client using the saved API key and the shared retry policy.
+func newOpenAIClient() (*openai.Client, error) {
+	log, err := logger.GetLogger()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logger: %w", err)
+	}
+
+	config := openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+	config.HTTPClient = &retryingDoer{
+		client: &http.Client{},
+		policy: RetryPolicy{
+			MaxRetries: getAPILimits().MaxRetries,
+			BaseDelay:  retryBaseDelay,
+			MaxDelay:   retryMaxDelay,
+		},
+		logger: log,
+	}
+
+	return openai.NewClientWithConfig(config), nil
+}
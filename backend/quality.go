@@ -0,0 +1,368 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"infinity-subtitle/backend/database"
+	"os"
+	"strconv"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+const (
+	QualityMethodRating          = "rating"
+	QualityMethodBackTranslation = "back_translation"
+
+	defaultQualityMinScore = 60
+
+	UsageOperationQuality = "quality"
+)
+
+// QualityScore rates one translated subtitle on a 0-100 scale.
+type QualityScore struct {
+	Score   int    `json:"score"`
+	Method  string `json:"method"`
+	Flagged bool   `json:"flagged"`
+	// Note is the reviewer's comment or the back translation
+	Note string `json:"note"`
+}
+
+// QualityReport summarises a quality check of one language.
+type QualityReport struct {
+	Language     string  `json:"language"`
+	Method       string  `json:"method"`
+	Checked      int     `json:"checked"`
+	FlaggedIDs   []int   `json:"flagged_ids"`
+	AverageScore float64 `json:"average_score"`
+}
+
+// QualitySettings selects the optional check run after queue translations.
+// An empty method disables it.
+type QualitySettings struct {
+	Method   string `json:"method"`
+	MinScore int    `json:"min_score"`
+}
+
+type qualityRating struct {
+	ID    int    `json:"id"`
+	Score int    `json:"score"`
+	Issue string `json:"issue"`
+}
+
+type qualityRatingResponse struct {
+	Ratings []qualityRating `json:"ratings"`
+}
+
+var qualityRatingSchema = jsonschema.Definition{
+	Type: jsonschema.Object,
+	Properties: map[string]jsonschema.Definition{
+		"ratings": {
+			Type: jsonschema.Array,
+			Items: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"id":    {Type: jsonschema.Integer},
+					"score": {Type: jsonschema.Integer, Description: "0 (wrong) to 100 (perfect)"},
+					"issue": {Type: jsonschema.String, Description: "short description of the problem, empty if none"},
+				},
+				Required:             []string{"id", "score", "issue"},
+				AdditionalProperties: false,
+			},
+		},
+	},
+	Required:             []string{"ratings"},
+	AdditionalProperties: false,
+}
+
+func getQualitySettings() QualitySettings {
+	return QualitySettings{
+		Method:   os.Getenv("TRANSLATION_QA_METHOD"),
+		MinScore: min(getEnvInt("TRANSLATION_QA_MIN_SCORE", defaultQualityMinScore), 100),
+	}
+}
+
+func (s *Setting) GetQualitySettings() (QualitySettings, error) {
+	return getQualitySettings(), nil
+}
+
+func (s *Setting) SaveQualitySettings(settings QualitySettings) error {
+	if settings.Method != "" && settings.Method != QualityMethodRating && settings.Method != QualityMethodBackTranslation {
+		return fmt.Errorf("unknown quality method: %s", settings.Method)
+	}
+	if settings.MinScore < 0 || settings.MinScore > 100 {
+		return errors.New("minimum score must be between 0 and 100")
+	}
+
+	return saveEnvValues(map[string]string{
+		"TRANSLATION_QA_METHOD":    settings.Method,
+		"TRANSLATION_QA_MIN_SCORE": strconv.Itoa(settings.MinScore),
+	})
+}
+
+// CheckTranslationQuality scores every translated subtitle of a movie in the
+// target language and flags the ones below the configured minimum score.
+func (s Subtitle) CheckTranslationQuality(movieId int, sourceLanguage string, targetLanguage string,
+	method string) (QualityReport, error) {
+	return checkTranslationQuality(context.Background(), 0, movieId, sourceLanguage, targetLanguage, method)
+}
+
+// ResolveQualityFlag clears the review flag of a subtitle once a person has checked it.
+func (s Subtitle) ResolveQualityFlag(subtitleID int, language string) error {
+	db := database.GetDB()
+	_, err := db.Exec(`
+		UPDATE subtitles
+		SET quality = json_set(quality, '$.' || ? || '.flagged', json('false'))
+		WHERE id = ? AND json_extract(quality, '$.' || ?) IS NOT NULL
+	`, language, subtitleID, language)
+	if err != nil {
+		return fmt.Errorf("failed to resolve quality flag: %w", err)
+	}
+	return nil
+}
+
+func checkTranslationQuality(ctx context.Context, queueID int, movieId int, sourceLanguage string,
+	targetLanguage string, method string) (QualityReport, error) {
+	report := QualityReport{Language: targetLanguage, Method: method, FlaggedIDs: []int{}}
+
+	if method != QualityMethodRating && method != QualityMethodBackTranslation {
+		return report, fmt.Errorf("unknown quality method: %s", method)
+	}
+
+	movie, err := NewMovie().GetMovieByID(movieId)
+	if err != nil {
+		return report, fmt.Errorf("failed to get movie: %w", err)
+	}
+
+	subtitles, err := getAllSubtitles(movieId)
+	if err != nil {
+		return report, err
+	}
+
+	var texts []TextToTranslate
+	sources := make(map[int]string)
+	for _, subtitle := range subtitles {
+		if subtitle.Content[sourceLanguage] == "" || subtitle.Content[targetLanguage] == "" {
+			continue
+		}
+		texts = append(texts, TextToTranslate{
+			ID:          subtitle.ID,
+			SourceText:  subtitle.Content[sourceLanguage],
+			Translation: subtitle.Content[targetLanguage],
+		})
+		sources[subtitle.ID] = subtitle.Content[sourceLanguage]
+	}
+
+	if len(texts) == 0 {
+		return report, nil
+	}
+
+	ts, err := NewTranslationService()
+	if err != nil {
+		return report, fmt.Errorf("failed to create translation service: %w", err)
+	}
+	defer ts.Close()
+	ts.usage = usageKey{MovieID: movieId, QueueID: queueID, Language: targetLanguage}
+
+	sourceName := movie.Languages[sourceLanguage]
+	targetName := movie.Languages[targetLanguage]
+
+	var scores map[int]QualityScore
+	if method == QualityMethodRating {
+		scores, err = ts.rateTranslations(ctx, texts, sourceName, targetName)
+	} else {
+		scores, err = ts.backTranslate(ctx, texts, sources, sourceName, targetName)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	minScore := getQualitySettings().MinScore
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return report, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	total := 0
+	for _, text := range texts {
+		score, ok := scores[text.ID]
+		if !ok {
+			continue
+		}
+		score.Flagged = score.Score < minScore
+
+		scoreJson, err := json.Marshal(score)
+		if err != nil {
+			return report, fmt.Errorf("failed to marshal quality score: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE subtitles
+			SET quality = json_set(COALESCE(quality, '{}'), '$.' || ?, json(?))
+			WHERE id = ?
+		`, targetLanguage, string(scoreJson), text.ID)
+		if err != nil {
+			return report, fmt.Errorf("failed to save quality score: %w", err)
+		}
+
+		report.Checked++
+		total += score.Score
+		if score.Flagged {
+			report.FlaggedIDs = append(report.FlaggedIDs, text.ID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return report, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if report.Checked > 0 {
+		report.AverageScore = float64(total) / float64(report.Checked)
+	}
+
+	return report, nil
+}
+
+// rateTranslations asks the model to score each translation.
+func (ts *TranslationService) rateTranslations(ctx context.Context, texts []TextToTranslate,
+	sourceLang, targetLang string) (map[int]QualityScore, error) {
+	return ts.scoreWithModel(ctx, texts, QualityMethodRating, func(payload []byte) string {
+		return fmt.Sprintf("Rate how well each `translation` below renders its `source_text` from %s to %s "+
+			"as a subtitle. Score meaning, fluency and tone from 0 to 100 and name the main issue, if any.\n\n%s",
+			sourceLang, targetLang, payload)
+	})
+}
+
+// backTranslate translates the translations back to the source language and
+// has the model judge whether the round trip still means the same as the
+// original. The note is the back translation, followed by the issue found.
+func (ts *TranslationService) backTranslate(ctx context.Context, texts []TextToTranslate, sources map[int]string,
+	sourceLang, targetLang string) (map[int]QualityScore, error) {
+	reverse := make([]TextToTranslate, 0, len(texts))
+	for _, text := range texts {
+		reverse = append(reverse, TextToTranslate{ID: text.ID, SourceText: text.Translation})
+	}
+
+	backTranslations, batchErrors := ts.processBatch(ctx, reverse, targetLang, sourceLang)
+	if len(backTranslations) == 0 && len(batchErrors) > 0 {
+		return nil, fmt.Errorf("failed to back translate: %s", batchErrors[0].Error)
+	}
+
+	comparisons := make([]TextToTranslate, 0, len(backTranslations))
+	for _, back := range backTranslations {
+		if back.Translation == "" {
+			continue
+		}
+		comparisons = append(comparisons, TextToTranslate{
+			ID:          back.ID,
+			SourceText:  sources[back.ID],
+			Translation: back.Translation,
+		})
+	}
+	if len(comparisons) == 0 {
+		return map[int]QualityScore{}, nil
+	}
+
+	scores, err := ts.scoreWithModel(ctx, comparisons, QualityMethodBackTranslation, func(payload []byte) string {
+		return fmt.Sprintf("Each `translation` below was translated from %s to %s and back. Rate from 0 to 100 "+
+			"whether it means the same as its `source_text`: paraphrases and different wording are fine, "+
+			"changed facts, negation, speaker intent or tone are not. Name the difference in meaning, if any.\n\n%s",
+			sourceLang, targetLang, payload)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, comparison := range comparisons {
+		score, ok := scores[comparison.ID]
+		if !ok {
+			continue
+		}
+		issue := score.Note
+		score.Note = comparison.Translation
+		if issue != "" {
+			score.Note += "\n" + issue
+		}
+		scores[comparison.ID] = score
+	}
+
+	return scores, nil
+}
+
+// scoreWithModel sends the texts to the model in batches with the prompt made
+// of each batch, and reads back a score and issue for each text.
+func (ts *TranslationService) scoreWithModel(ctx context.Context, texts []TextToTranslate, method string,
+	prompt func(payload []byte) string) (map[int]QualityScore, error) {
+	scores := make(map[int]QualityScore)
+
+	for start := 0; start < len(texts); start += translationBatchSize {
+		batch := texts[start:min(start+translationBatchSize, len(texts))]
+
+		if err := ts.limiter.Wait(ctx, estimateBatchTokens(batch)); err != nil {
+			return nil, fmt.Errorf("rate limit exceeded: %w", err)
+		}
+
+		payload, err := json.Marshal(batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal translations: %w", err)
+		}
+
+		resp, err := ts.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+			Model: translationModel,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: "You are a strict subtitle translation reviewer. Reply with JSON only."},
+				{Role: openai.ChatMessageRoleUser, Content: prompt(payload)},
+			},
+			ResponseFormat: &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+				JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+					Name:   "translation_ratings",
+					Schema: &qualityRatingSchema,
+					Strict: true,
+				},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to rate translations: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return nil, errors.New("no response from OpenAI")
+		}
+
+		recordUsage(ts.usage, translationModel, UsageOperationQuality,
+			resp.Usage.PromptTokens, resp.Usage.CompletionTokens, 0)
+
+		raw, err := extractJSON(resp.Choices[0].Message.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ratings: %w", err)
+		}
+		var ratings qualityRatingResponse
+		if err := json.Unmarshal(raw, &ratings); err != nil {
+			return nil, fmt.Errorf("failed to parse ratings: %w", err)
+		}
+
+		inBatch := make(map[int]bool, len(batch))
+		for _, text := range batch {
+			inBatch[text.ID] = true
+		}
+		for _, rating := range ratings.Ratings {
+			if !inBatch[rating.ID] {
+				ts.logger.Warn("Rejected rating: id %d was not in the batch", rating.ID)
+				continue
+			}
+			scores[rating.ID] = QualityScore{
+				Score:  max(0, min(rating.Score, 100)),
+				Method: method,
+				Note:   rating.Issue,
+			}
+		}
+	}
+
+	return scores, nil
+}
@@ -0,0 +1,355 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultMaxCharsPerLine = 42
+	defaultMaxLines        = 2
+	defaultMaxCPS          = 17
+
+	// condenseAttempts is how often translations over the limits are rewritten
+	condenseAttempts = 2
+)
+
+// SubtitleConstraints limits how much text a cue may show. A zero value
+// disables that limit.
+type SubtitleConstraints struct {
+	MaxCharsPerLine int `json:"max_chars_per_line"`
+	MaxLines        int `json:"max_lines"`
+	// MaxCPS is the reading speed in characters per second of cue duration
+	MaxCPS int `json:"max_cps"`
+}
+
+func getSubtitleConstraints() SubtitleConstraints {
+	return SubtitleConstraints{
+		MaxCharsPerLine: getEnvInt("SUBTITLE_MAX_CHARS_PER_LINE", defaultMaxCharsPerLine),
+		MaxLines:        getEnvInt("SUBTITLE_MAX_LINES", defaultMaxLines),
+		MaxCPS:          getEnvInt("SUBTITLE_MAX_CPS", defaultMaxCPS),
+	}
+}
+
+func (s *Setting) GetSubtitleConstraints() (SubtitleConstraints, error) {
+	return getSubtitleConstraints(), nil
+}
+
+func (s *Setting) SaveSubtitleConstraints(constraints SubtitleConstraints) error {
+	if constraints.MaxCharsPerLine < 0 || constraints.MaxLines < 0 || constraints.MaxCPS < 0 {
+		return errors.New("subtitle limits cannot be negative")
+	}
+
+	return saveEnvValues(map[string]string{
+		"SUBTITLE_MAX_CHARS_PER_LINE": strconv.Itoa(constraints.MaxCharsPerLine),
+		"SUBTITLE_MAX_LINES":          strconv.Itoa(constraints.MaxLines),
+		"SUBTITLE_MAX_CPS":            strconv.Itoa(constraints.MaxCPS),
+	})
+}
+
+func (c SubtitleConstraints) enabled() bool {
+	return c.MaxCharsPerLine > 0 || c.MaxLines > 0 || c.MaxCPS > 0
+}
+
+// maxChars returns the most characters a cue of the given timing may show,
+// or 0 if it is unlimited.
+func (c SubtitleConstraints) maxChars(startTime string, endTime string) int {
+	limit := 0
+	if c.MaxCharsPerLine > 0 && c.MaxLines > 0 {
+		limit = c.MaxCharsPerLine * c.MaxLines
+	}
+
+	if c.MaxCPS > 0 {
+		start, startErr := parseTimestamp(startTime)
+		end, endErr := parseTimestamp(endTime)
+		if startErr == nil && endErr == nil && end > start {
+			byDuration := max(int(end.Seconds()*float64(c.MaxCPS)), 1)
+			if limit == 0 || byDuration < limit {
+				limit = byDuration
+			}
+		}
+	}
+
+	return limit
+}
+
+// prompt describes the limits to the model.
+func (c SubtitleConstraints) prompt() string {
+	var rules []string
+	if c.MaxLines > 0 {
+		rules = append(rules, fmt.Sprintf("at most %d lines", c.MaxLines))
+	}
+	if c.MaxCharsPerLine > 0 {
+		rules = append(rules, fmt.Sprintf("at most %d characters per line", c.MaxCharsPerLine))
+	}
+
+	prompt := ""
+	if len(rules) > 0 {
+		prompt = "Each translation must fit on " + strings.Join(rules, " with ") + ". "
+	}
+	return prompt + "When an item has `max_chars`, its translation must not be longer than that many characters; " +
+		"condense the wording rather than dropping meaning."
+}
+
+// fits reports whether text, once wrapped, stays within the limits.
+func (c SubtitleConstraints) fits(text string, maxChars int) bool {
+	if maxChars > 0 && readingLength(text) > maxChars {
+		return false
+	}
+
+	lines := strings.Split(c.wrap(text), "\n")
+	if c.MaxLines > 0 && len(lines) > c.MaxLines {
+		return false
+	}
+	if c.MaxCharsPerLine > 0 {
+		for _, line := range lines {
+			if utf8.RuneCountInString(line) > c.MaxCharsPerLine {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// readingLength counts the characters a viewer reads, ignoring line breaks
+// and repeated spaces.
+func readingLength(text string) int {
+	return utf8.RuneCountInString(normalizeText(text))
+}
+
+// wrap re-breaks text into balanced lines. Dialogue lines starting with a dash
+// belong to different speakers and are wrapped on their own.
+func (c SubtitleConstraints) wrap(text string) string {
+	text = strings.TrimSpace(text)
+	if c.MaxCharsPerLine <= 0 || text == "" {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	if len(lines) > 1 && isDialogue(lines) {
+		wrapped := make([]string, 0, len(lines))
+		for _, line := range lines {
+			wrapped = append(wrapped, wrapLine(line, c.MaxCharsPerLine))
+		}
+		return strings.Join(wrapped, "\n")
+	}
+
+	return wrapLine(text, c.MaxCharsPerLine)
+}
+
+func isDialogue(lines []string) bool {
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "-") {
+			return false
+		}
+	}
+	return true
+}
+
+// wrapLine splits text into the fewest lines of at most maxChars characters,
+// balancing their lengths and preferring breaks after punctuation and before
+// conjunctions. Text without spaces, such as Chinese or Japanese, is broken
+// between characters.
+func wrapLine(text string, maxChars int) string {
+	words := strings.Fields(text)
+	separator := " "
+	if len(words) == 1 {
+		words = strings.Split(words[0], "")
+		separator = ""
+	}
+
+	total := utf8.RuneCountInString(strings.Join(words, separator))
+	if total <= maxChars {
+		return strings.Join(words, separator)
+	}
+
+	lineCount := int(math.Ceil(float64(total) / float64(maxChars)))
+	for ; lineCount < len(words); lineCount++ {
+		if breaks, ok := balancedBreaks(words, separator, lineCount, maxChars); ok {
+			return joinLines(words, separator, breaks)
+		}
+	}
+
+	// A single word is longer than a line; give every word its own line
+	return strings.Join(words, "\n")
+}
+
+// balancedBreaks chooses where to end each of lineCount lines. It returns the
+// index of the first word of every line after the first.
+func balancedBreaks(words []string, separator string, lineCount int, maxChars int) ([]int, bool) {
+	n := len(words)
+	lengths := make([]int, n+1)
+	for i, word := range words {
+		lengths[i+1] = lengths[i] + utf8.RuneCountInString(word)
+	}
+	lineLength := func(from, to int) int {
+		return lengths[to] - lengths[from] + (to-from-1)*len(separator)
+	}
+
+	target := float64(lineLength(0, n)) / float64(lineCount)
+	inf := math.Inf(1)
+
+	// cost[k][i] is the best cost of setting the first i words on k lines
+	cost := make([][]float64, lineCount+1)
+	from := make([][]int, lineCount+1)
+	for k := range cost {
+		cost[k] = make([]float64, n+1)
+		from[k] = make([]int, n+1)
+		for i := range cost[k] {
+			cost[k][i] = inf
+		}
+	}
+	cost[0][0] = 0
+
+	for k := 1; k <= lineCount; k++ {
+		for i := k; i <= n; i++ {
+			for j := k - 1; j < i; j++ {
+				if cost[k-1][j] == inf {
+					continue
+				}
+				length := lineLength(j, i)
+				if length > maxChars {
+					continue
+				}
+				c := cost[k-1][j] + math.Pow(float64(length)-target, 2)
+				if i < n {
+					c -= breakBonus(words[i-1], words[i])
+				}
+				if c < cost[k][i] {
+					cost[k][i] = c
+					from[k][i] = j
+				}
+			}
+		}
+	}
+
+	if cost[lineCount][n] == inf {
+		return nil, false
+	}
+
+	breaks := make([]int, lineCount-1)
+	for k, i := lineCount, n; k > 1; k-- {
+		i = from[k][i]
+		breaks[k-2] = i
+	}
+	return breaks, true
+}
+
+// breakConjunctions are words a new line reads naturally from.
+var breakConjunctions = map[string]bool{
+	"and": true, "but": true, "or": true, "so": true, "because": true, "if": true,
+	"when": true, "while": true, "that": true, "which": true, "who": true,
+	"et": true, "mais": true, "ou": true, "und": true, "aber": true, "oder": true,
+	"y": true, "pero": true, "e": true, "ma": true,
+}
+
+// breakBonus rates a line break between two words.
+func breakBonus(before string, after string) float64 {
+	last, _ := utf8.DecodeLastRuneInString(before)
+	switch {
+	case strings.ContainsRune(".!?。！？", last):
+		return 60
+	case strings.ContainsRune(",;:，、；：", last):
+		return 40
+	case unicode.IsPunct(last):
+		return 20
+	case breakConjunctions[strings.ToLower(after)]:
+		return 20
+	}
+	return 0
+}
+
+func joinLines(words []string, separator string, breaks []int) string {
+	var lines []string
+	start := 0
+	for _, end := range append(breaks, len(words)) {
+		lines = append(lines, strings.Join(words[start:end], separator))
+		start = end
+	}
+	return strings.Join(lines, "\n")
+}
+
+// enforceConstraints re-wraps translations and asks for shorter wording of
+// the ones that still break the limits. Rewrites that aren't shorter are
+// ignored, and anything still too long is left for the caller to report.
+func (ts *TranslationService) enforceConstraints(ctx context.Context, translations []TextToTranslate,
+	targetLang string) []TextToTranslate {
+	for attempt := 0; ; attempt++ {
+		tooLong := make([]TextToTranslate, 0)
+		for i := range translations {
+			if translations[i].Translation == "" {
+				continue
+			}
+			translations[i].Translation = ts.constraints.wrap(translations[i].Translation)
+			if !ts.constraints.fits(translations[i].Translation, translations[i].MaxChars) {
+				tooLong = append(tooLong, translations[i])
+			}
+		}
+
+		if len(tooLong) == 0 || attempt == condenseAttempts {
+			return translations
+		}
+
+		ts.logger.Info("Condensing %d translations over the subtitle limits, attempt %d", len(tooLong), attempt)
+
+		if err := ts.limiter.Wait(ctx, estimateBatchTokens(tooLong)); err != nil {
+			ts.logger.Error("Failed to condense translations: %v", err)
+			return translations
+		}
+
+		condensed, err := ts.condenseTranslations(ctx, tooLong, targetLang)
+		if err != nil {
+			ts.logger.Error("Failed to condense translations: %v", err)
+			return translations
+		}
+
+		shorter := make(map[int]string)
+		for _, text := range condensed {
+			shorter[text.ID] = text.Translation
+		}
+		for i := range translations {
+			rewrite, ok := shorter[translations[i].ID]
+			if ok && rewrite != "" && readingLength(rewrite) < readingLength(translations[i].Translation) {
+				translations[i].Translation = rewrite
+			}
+		}
+	}
+}
+
+// condenseTranslations asks for a shorter version of each translation.
+func (ts *TranslationService) condenseTranslations(ctx context.Context, tooLong []TextToTranslate,
+	targetLang string) ([]TextToTranslate, error) {
+	items := make([]TextToTranslate, 0, len(tooLong))
+	for _, text := range tooLong {
+		maxChars := text.MaxChars
+		if maxChars == 0 && ts.constraints.MaxCharsPerLine > 0 && ts.constraints.MaxLines > 0 {
+			maxChars = ts.constraints.MaxCharsPerLine * ts.constraints.MaxLines
+		}
+		items = append(items, TextToTranslate{
+			ID:          text.ID,
+			SourceText:  text.SourceText,
+			Translation: text.Translation,
+			MaxChars:    maxChars,
+		})
+	}
+
+	payload, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal translations to condense: %w", err)
+	}
+
+	prompt := fmt.Sprintf("The %s subtitle translations below are too long to be read in time. Rewrite every "+
+		"`translation` more concisely, keeping its meaning and tone, so that it has no more than `max_chars` "+
+		"characters. Keep `id` and `source_text` exactly as given and return every item. %s\n\n%s",
+		targetLang, ts.constraints.prompt(), payload)
+
+	return ts.requestTranslations(ctx, prompt, items, UsageOperationTranslation)
+}
@@ -0,0 +1,350 @@
+package backend
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultMaxCueDurationMs = 6000
+	defaultMinGapMs         = 80
+	defaultPauseMs          = 600
+
+	// minCueDuration is the shortest time a cue stays on screen when the next allows it
+	minCueDuration = time.Second
+	// alignLookahead is how many words or tokens are skipped to realign punctuation
+	alignLookahead = 3
+	// segmentEndTolerance allows word timings to end slightly after their segment
+	segmentEndTolerance = 100 * time.Millisecond
+)
+
+// SegmentationSettings controls how transcribed words are grouped into cues.
+// Line lengths come from the subtitle limits.
+type SegmentationSettings struct {
+	MaxCueDurationMs int `json:"max_cue_duration_ms"`
+	// MinGapMs is the least time between two cues
+	MinGapMs int `json:"min_gap_ms"`
+	// PauseMs is a silence long enough to always start a new cue, 0 to ignore pauses
+	PauseMs int `json:"pause_ms"`
+}
+
+func getSegmentationSettings() SegmentationSettings {
+	return SegmentationSettings{
+		MaxCueDurationMs: getEnvInt("SEGMENT_MAX_CUE_DURATION_MS", defaultMaxCueDurationMs),
+		MinGapMs:         getEnvInt("SEGMENT_MIN_GAP_MS", defaultMinGapMs),
+		PauseMs:          getEnvInt("SEGMENT_PAUSE_MS", defaultPauseMs),
+	}
+}
+
+func (s *Setting) GetSegmentationSettings() (SegmentationSettings, error) {
+	return getSegmentationSettings(), nil
+}
+
+func (s *Setting) SaveSegmentationSettings(settings SegmentationSettings) error {
+	if settings.MaxCueDurationMs < int(minCueDuration.Milliseconds()) {
+		return errors.New("cues must be allowed to last at least a second")
+	}
+	if settings.MinGapMs < 0 || settings.PauseMs < 0 {
+		return errors.New("gaps cannot be negative")
+	}
+
+	return saveEnvValues(map[string]string{
+		"SEGMENT_MAX_CUE_DURATION_MS": strconv.Itoa(settings.MaxCueDurationMs),
+		"SEGMENT_MIN_GAP_MS":          strconv.Itoa(settings.MinGapMs),
+		"SEGMENT_PAUSE_MS":            strconv.Itoa(settings.PauseMs),
+	})
+}
+
+// transcriptWord is a transcribed word with its timing. Text carries the
+// punctuation of the transcript.
+type transcriptWord struct {
+	Text  string
+	Start time.Duration
+	End   time.Duration
+	// Speaker is set by diarizing transcribers
+	Speaker string
+}
+
+// cueSegmenter groups words into cues.
+type cueSegmenter struct {
+	maxDuration time.Duration
+	minGap      time.Duration
+	pause       time.Duration
+	constraints SubtitleConstraints
+	// separator joins words; it is empty for languages written without spaces
+	separator string
+}
+
+func newCueSegmenter(separator string) cueSegmenter {
+	settings := getSegmentationSettings()
+	constraints := getSubtitleConstraints()
+	if constraints.MaxCharsPerLine <= 0 {
+		constraints.MaxCharsPerLine = defaultMaxCharsPerLine
+	}
+	if constraints.MaxLines <= 0 {
+		constraints.MaxLines = defaultMaxLines
+	}
+
+	return cueSegmenter{
+		maxDuration: time.Duration(settings.MaxCueDurationMs) * time.Millisecond,
+		minGap:      time.Duration(settings.MinGapMs) * time.Millisecond,
+		pause:       time.Duration(settings.PauseMs) * time.Millisecond,
+		constraints: constraints,
+		separator:   separator,
+	}
+}
+
+// segmentTranscription builds SRT cues from a transcription. Without word
+// timestamps the words of each segment are timed by their length.
+func segmentTranscription(transcription Transcription) []SRTCue {
+	separator := " "
+	if !usesSpaces(transcription.Text) {
+		separator = ""
+	}
+	segmenter := newCueSegmenter(separator)
+
+	if len(transcription.Words) == 0 {
+		var words []transcriptWord
+		for _, segment := range transcription.Segments {
+			segmentWords := spreadSegment(segment.Text, segment.Start, segment.End, separator)
+			for i := range segmentWords {
+				segmentWords[i].Speaker = segment.Speaker
+			}
+			words = append(words, segmentWords...)
+		}
+		return segmenter.cues(words)
+	}
+
+	words := append([]transcriptWord(nil), transcription.Words...)
+	punctuateWords(words, transcription.Segments, separator)
+
+	return segmenter.cues(words)
+}
+
+// spreadSegment splits a segment into words and shares its time among them
+// by their length.
+func spreadSegment(text string, start time.Duration, end time.Duration, separator string) []transcriptWord {
+	tokens := strings.Fields(text)
+	if separator == "" {
+		tokens = strings.Split(strings.Join(tokens, ""), "")
+	}
+
+	total := 0
+	for _, token := range tokens {
+		total += utf8.RuneCountInString(token)
+	}
+	if total == 0 {
+		return nil
+	}
+
+	words := make([]transcriptWord, 0, len(tokens))
+	done := 0
+	for _, token := range tokens {
+		wordStart := start + (end-start)*time.Duration(done)/time.Duration(total)
+		done += utf8.RuneCountInString(token)
+		words = append(words, transcriptWord{
+			Text:  token,
+			Start: wordStart,
+			End:   start + (end-start)*time.Duration(done)/time.Duration(total),
+		})
+	}
+	return words
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// usesSpaces reports whether the text is written with spaces between words.
+func usesSpaces(text string) bool {
+	runes := utf8.RuneCountInString(text)
+	return runes == 0 || strings.Count(text, " ")*20 >= runes
+}
+
+// transcriptSegment is a sentence or phrase of the transcript as the API
+// punctuated it.
+type transcriptSegment struct {
+	Text    string
+	Start   time.Duration
+	End     time.Duration
+	Speaker string
+}
+
+// punctuateWords copies the punctuation of the segment texts onto the words,
+// which the API returns bare. Words that cannot be matched keep their text,
+// and the last word of a segment ending a sentence gets its full stop. Words
+// without a speaker take the one of their segment.
+func punctuateWords(words []transcriptWord, segments []transcriptSegment, separator string) {
+	// Text without spaces cannot be split into tokens to align
+	if separator != "" {
+		alignPunctuation(words, segments)
+	}
+
+	next := 0
+	for _, segment := range segments {
+		last := -1
+		for next < len(words) && words[next].End <= segment.End+segmentEndTolerance {
+			if words[next].Speaker == "" {
+				words[next].Speaker = segment.Speaker
+			}
+			last = next
+			next++
+		}
+		text := strings.TrimSpace(segment.Text)
+		if last >= 0 && endsSentence(text) && !endsSentence(words[last].Text) {
+			mark, _ := utf8.DecodeLastRuneInString(text)
+			words[last].Text += string(mark)
+		}
+	}
+}
+
+func alignPunctuation(words []transcriptWord, segments []transcriptSegment) {
+	var tokens []string
+	for _, segment := range segments {
+		tokens = append(tokens, strings.Fields(segment.Text)...)
+	}
+
+	w, t := 0, 0
+	for w < len(words) && t < len(tokens) {
+		if bareWord(words[w].Text) == bareWord(tokens[t]) {
+			words[w].Text = tokens[t]
+			w++
+			t++
+			continue
+		}
+
+		// Skip whichever side has an extra word, if a match follows shortly
+		realigned := false
+		for skip := 1; skip <= alignLookahead && !realigned; skip++ {
+			if t+skip < len(tokens) && bareWord(words[w].Text) == bareWord(tokens[t+skip]) {
+				t += skip
+				realigned = true
+			} else if w+skip < len(words) && bareWord(words[w+skip].Text) == bareWord(tokens[t]) {
+				w += skip
+				realigned = true
+			}
+		}
+		if !realigned {
+			w++
+			t++
+		}
+	}
+}
+
+// bareWord strips punctuation and case for comparing words.
+func bareWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}))
+}
+
+func endsSentence(text string) bool {
+	last, _ := utf8.DecodeLastRuneInString(strings.TrimRight(text, "\"'”’)»"))
+	return strings.ContainsRune(".!?…。！？", last)
+}
+
+func endsClause(text string) bool {
+	last, _ := utf8.DecodeLastRuneInString(text)
+	return strings.ContainsRune(",;:—，、；：", last)
+}
+
+// cues groups words into cues. A cue ends when the speaker changes, at a
+// pause, at the end of a sentence if it can be shown long enough, or when the next word would
+// break the duration or length limit; in that case the cue is cut after its
+// last clause if that isn't too early.
+func (s cueSegmenter) cues(words []transcriptWord) []SRTCue {
+	var groups [][]transcriptWord
+	var current []transcriptWord
+
+	maxChars := s.constraints.MaxCharsPerLine * s.constraints.MaxLines
+	for i, word := range words {
+		if word.Text == "" {
+			continue
+		}
+
+		if len(current) > 0 {
+			last := current[len(current)-1]
+			text := s.join(append(current[:len(current):len(current)], word))
+			switch {
+			case word.Speaker != last.Speaker:
+				groups = append(groups, current)
+				current = nil
+			case s.pause > 0 && word.Start-last.End >= s.pause:
+				groups = append(groups, current)
+				current = nil
+			case word.End-current[0].Start > s.maxDuration || utf8.RuneCountInString(text) > maxChars:
+				keep := clauseBreak(current)
+				groups = append(groups, current[:keep])
+				current = append([]transcriptWord(nil), current[keep:]...)
+			}
+		}
+		current = append(current, word)
+
+		// A sentence ends the cue if the cue can stay up long enough before the next
+		if endsSentence(word.Text) {
+			if i+1 == len(words) || words[i+1].Start-current[0].Start >= minCueDuration+s.minGap {
+				groups = append(groups, current)
+				current = nil
+			}
+		}
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+
+	cues := make([]SRTCue, 0, len(groups))
+	starts := make([]time.Duration, len(groups))
+	ends := make([]time.Duration, len(groups))
+	for i, group := range groups {
+		starts[i] = group[0].Start
+		ends[i] = max(group[len(group)-1].End, starts[i])
+	}
+
+	for i := range groups {
+		limit := time.Duration(math.MaxInt64)
+		if i+1 < len(groups) {
+			limit = starts[i+1] - s.minGap
+		}
+		// Keep short cues up long enough to be read, without running into the next
+		if ends[i]-starts[i] < minCueDuration {
+			ends[i] = starts[i] + minCueDuration
+		}
+		if ends[i] > limit {
+			ends[i] = max(limit, starts[i])
+		}
+
+		cues = append(cues, SRTCue{
+			SlNo:      i + 1,
+			StartTime: formatTimestamp(starts[i]),
+			EndTime:   formatTimestamp(ends[i]),
+			Text:      s.constraints.wrap(s.join(groups[i])) + "\n",
+			Speaker:   groups[i][0].Speaker,
+		})
+	}
+
+	return cues
+}
+
+// clauseBreak returns how many words of a full cue to keep: up to its last
+// clause ending after the first third, or all of them.
+func clauseBreak(words []transcriptWord) int {
+	for i := len(words) - 2; i >= len(words)/3; i-- {
+		if endsClause(words[i].Text) || endsSentence(words[i].Text) {
+			return i + 1
+		}
+	}
+	return len(words)
+}
+
+func (s cueSegmenter) join(words []transcriptWord) string {
+	texts := make([]string, 0, len(words))
+	for _, word := range words {
+		texts = append(texts, word.Text)
+	}
+	return strings.Join(texts, s.separator)
+}
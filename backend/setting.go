@@ -1,8 +1,10 @@
 package backend
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	_ "github.com/joho/godotenv/autoload"
@@ -10,55 +12,107 @@ import (
 
 type Setting struct{}
 
+// APILimits configures how fast and how persistently OpenAI is called.
+type APILimits struct {
+	RequestsPerMinute int `json:"requests_per_minute"`
+	TokensPerMinute   int `json:"tokens_per_minute"`
+	MaxRetries        int `json:"max_retries"`
+}
+
 func NewSetting() *Setting {
 	return &Setting{}
 }
 
-func (s *Setting) SaveOpenAIKey(key string) error {
+// saveEnvValues writes the given keys to the .env file, replacing existing
+// values, and applies them to the running process.
+func saveEnvValues(values map[string]string) error {
 	// Read the current .env file
 	content, err := os.ReadFile(".env")
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Create new .env file with the API key
-			var b []byte
-			b = fmt.Appendf(b, "OPENAI_API_KEY=%s\n", key)
-			err = os.WriteFile(".env", b, 0644)
-			if err != nil {
-				return fmt.Errorf("failed to create .env file: %w", err)
-			}
-			return nil
-		}
+	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to read .env file: %w", err)
 	}
 
 	lines := strings.Split(string(content), "\n")
-	keyFound := false
+	found := make(map[string]bool)
 	newLines := make([]string, 0)
 
-	// Look for existing OPENAI_API_KEY
+	// Replace existing keys
 	for _, line := range lines {
-		if strings.HasPrefix(line, "OPENAI_API_KEY=") {
-			newLines = append(newLines, fmt.Sprintf("OPENAI_API_KEY=%s", key))
-			keyFound = true
+		key, _, ok := strings.Cut(line, "=")
+		if value, exists := values[key]; ok && exists {
+			newLines = append(newLines, fmt.Sprintf("%s=%s", key, value))
+			found[key] = true
 		} else if line != "" {
 			newLines = append(newLines, line)
 		}
 	}
 
-	// If key wasn't found, add it
-	if !keyFound {
-		newLines = append(newLines, fmt.Sprintf("OPENAI_API_KEY=%s", key))
+	// Add keys that weren't found
+	for key, value := range values {
+		if !found[key] {
+			newLines = append(newLines, fmt.Sprintf("%s=%s", key, value))
+		}
 	}
 
 	// Write back to .env file
-	err = os.WriteFile(".env", []byte(strings.Join(newLines, "\n")), 0644)
+	err = os.WriteFile(".env", []byte(strings.Join(newLines, "\n")+"\n"), 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write .env file: %w", err)
 	}
 
+	for key, value := range values {
+		os.Setenv(key, value)
+	}
+
 	return nil
 }
 
+// getEnvInt reads a non-negative integer from the environment, falling back to def.
+func getEnvInt(key string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return def
+	}
+	return value
+}
+
+func (s *Setting) SaveOpenAIKey(key string) error {
+	return saveEnvValues(map[string]string{"OPENAI_API_KEY": key})
+}
+
 func (s *Setting) GetOpenAIKey() (string, error) {
 	return os.Getenv("OPENAI_API_KEY"), nil
 }
+
+func getAPILimits() APILimits {
+	return APILimits{
+		RequestsPerMinute: max(getEnvInt("OPENAI_REQUESTS_PER_MINUTE", defaultRequestsPerMinute), 1),
+		TokensPerMinute:   max(getEnvInt("OPENAI_TOKENS_PER_MINUTE", defaultTokensPerMinute), 1),
+		MaxRetries:        getEnvInt("OPENAI_MAX_RETRIES", defaultMaxRetries),
+	}
+}
+
+func (s *Setting) GetAPILimits() (APILimits, error) {
+	return getAPILimits(), nil
+}
+
+func (s *Setting) SaveAPILimits(limits APILimits) error {
+	if limits.RequestsPerMinute <= 0 || limits.TokensPerMinute <= 0 {
+		return errors.New("requests and tokens per minute must be greater than zero")
+	}
+	if limits.MaxRetries < 0 {
+		return errors.New("max retries cannot be negative")
+	}
+
+	err := saveEnvValues(map[string]string{
+		"OPENAI_REQUESTS_PER_MINUTE": strconv.Itoa(limits.RequestsPerMinute),
+		"OPENAI_TOKENS_PER_MINUTE":   strconv.Itoa(limits.TokensPerMinute),
+		"OPENAI_MAX_RETRIES":         strconv.Itoa(limits.MaxRetries),
+	})
+	if err != nil {
+		return err
+	}
+
+	resetAPILimiter()
+	return nil
+}
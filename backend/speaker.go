@@ -0,0 +1,165 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"infinity-subtitle/backend/database"
+)
+
+// maxSpeakerLength keeps speaker names to what fits an ASS actor field
+const maxSpeakerLength = 64
+
+// speakerLabelPattern matches the labels diarizing programs such as WhisperX
+// put before each cue, "[SPEAKER_00]: text" or "SPEAKER_00: text".
+var speakerLabelPattern = regexp.MustCompile(`^\[?(SPEAKER_\d+)\]?:\s*`)
+
+// SpeakerCount is a speaker of a movie and how many cues they speak.
+type SpeakerCount struct {
+	Speaker string `json:"speaker"`
+	Cues    int    `json:"cues"`
+}
+
+// splitVoiceTag separates a leading WebVTT voice tag, <v Name>, from the
+// text of a cue.
+func splitVoiceTag(text string) (string, string) {
+	if !strings.HasPrefix(text, "<v ") {
+		return "", text
+	}
+	end := strings.Index(text, ">")
+	if end < 0 {
+		return "", text
+	}
+	speaker := strings.TrimSpace(text[len("<v "):end])
+	text = strings.Replace(text[end+1:], "</v>", "", 1)
+	return speaker, text
+}
+
+// splitSpeakerLabel separates a diarization label or voice tag from the text
+// of a cue.
+func splitSpeakerLabel(text string) (string, string) {
+	if speaker, rest := splitVoiceTag(text); speaker != "" {
+		return speaker, rest
+	}
+	if match := speakerLabelPattern.FindStringSubmatch(text); match != nil {
+		return match[1], text[len(match[0]):]
+	}
+	return "", text
+}
+
+// normalizeSpeaker trims a speaker name and rejects characters that would
+// break the formats speakers are exported in.
+func normalizeSpeaker(speaker string) (string, error) {
+	speaker = strings.TrimSpace(speaker)
+	if len(speaker) > maxSpeakerLength {
+		return "", fmt.Errorf("speaker names are limited to %d characters", maxSpeakerLength)
+	}
+	if strings.ContainsAny(speaker, "<>,\n") {
+		return "", errors.New("speaker names cannot contain <, >, commas or line breaks")
+	}
+	return speaker, nil
+}
+
+// speakerReplacer drops the characters normalizeSpeaker rejects.
+var speakerReplacer = strings.NewReplacer("<", "", ">", "", ",", " ", "\r", " ", "\n", " ")
+
+// sanitizeSpeaker makes a speaker label read from an imported file or a
+// transcriber acceptable to normalizeSpeaker, instead of rejecting it.
+func sanitizeSpeaker(speaker string) string {
+	speaker = strings.Join(strings.Fields(speakerReplacer.Replace(speaker)), " ")
+	for len(speaker) > maxSpeakerLength {
+		_, size := utf8.DecodeLastRuneInString(speaker)
+		speaker = speaker[:len(speaker)-size]
+	}
+	return strings.TrimSpace(speaker)
+}
+
+// GetSpeakers lists the speakers of a movie with their number of cues.
+func (s Subtitle) GetSpeakers(movieID int) ([]SpeakerCount, error) {
+	db := database.GetDB()
+
+	rows, err := db.Query(`
+		SELECT speaker, COUNT(*) FROM subtitles
+		WHERE movie_id = ? AND speaker != ''
+		GROUP BY speaker
+		ORDER BY MIN(sl_no)
+	`, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get speakers: %w", err)
+	}
+	defer rows.Close()
+
+	speakers := []SpeakerCount{}
+	for rows.Next() {
+		var speaker SpeakerCount
+		if err := rows.Scan(&speaker.Speaker, &speaker.Cues); err != nil {
+			return nil, fmt.Errorf("failed to scan speaker: %w", err)
+		}
+		speakers = append(speakers, speaker)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get speakers: %w", err)
+	}
+
+	return speakers, nil
+}
+
+// RenameSpeaker renames a speaker in every cue of a movie, for example to
+// replace SPEAKER_00 with a character name. Renaming to an existing speaker
+// merges the two; renaming to an empty name clears the speaker.
+func (s Subtitle) RenameSpeaker(movieID int, from string, to string) (int64, error) {
+	to, err := normalizeSpeaker(to)
+	if err != nil {
+		return 0, err
+	}
+	if from == "" {
+		return 0, errors.New("speaker to rename is required")
+	}
+
+	db := database.GetDB()
+	result, err := db.Exec("UPDATE subtitles SET speaker = ?, updated_at = CURRENT_TIMESTAMP WHERE movie_id = ? AND speaker = ?",
+		to, movieID, from)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rename speaker: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// AssignSpeaker sets the speaker of the given cues.
+func (s Subtitle) AssignSpeaker(movieID int, ids []int, speaker string) error {
+	speaker, err := normalizeSpeaker(speaker)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("UPDATE subtitles SET speaker = ?, updated_at = CURRENT_TIMESTAMP WHERE movie_id = ? AND id = ?")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, id := range ids {
+		if _, err := stmt.Exec(speaker, movieID, id); err != nil {
+			return fmt.Errorf("failed to assign speaker: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
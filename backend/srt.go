@@ -0,0 +1,116 @@
+package backend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SRTCue is a single cue read from an SRT file.
+type SRTCue struct {
+	SlNo      int
+	StartTime string
+	EndTime   string
+	Text      string
+	// Speaker is read from a leading WebVTT voice tag, <v Name>
+	Speaker string
+}
+
+// parseSRT splits SRT content into cues. Text lines of a cue are kept with a
+// trailing newline each, as they are stored in the subtitles table.
+func parseSRT(content string) []SRTCue {
+	content = strings.ReplaceAll(strings.TrimSpace(content), "\r\n", "\n")
+	lines := strings.Split(content, "\n")
+
+	var cues []SRTCue
+	cue := SRTCue{}
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		slNo, err := strconv.Atoi(line)
+		if err == nil {
+			cue.SlNo = slNo
+			continue
+		}
+
+		if strings.Contains(line, "-->") {
+			parts := strings.Split(line, "-->")
+			if len(parts) == 2 {
+				cue.StartTime = strings.TrimSpace(parts[0])
+				cue.EndTime = strings.TrimSpace(parts[1])
+				continue
+			}
+		}
+
+		cue.Text += line + "\n"
+
+		nextLine := ""
+		if i+1 < len(lines) {
+			nextLine = strings.TrimSpace(lines[i+1])
+		}
+
+		if nextLine == "" {
+			cue.Speaker, cue.Text = splitVoiceTag(cue.Text)
+			cues = append(cues, cue)
+			cue = SRTCue{}
+		}
+	}
+
+	return cues
+}
+
+// parseTimestamp converts an SRT timestamp such as 00:01:02,345 to a duration.
+// A dot is accepted as the millisecond separator as well.
+func parseTimestamp(timestamp string) (time.Duration, error) {
+	var hours, minutes, seconds, millis int
+	normalized := strings.Replace(strings.TrimSpace(timestamp), ".", ",", 1)
+	_, err := fmt.Sscanf(normalized, "%d:%d:%d,%d", &hours, &minutes, &seconds, &millis)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q: %w", timestamp, err)
+	}
+
+	return time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second +
+		time.Duration(millis)*time.Millisecond, nil
+}
+
+// formatTimestamp converts a duration to an SRT timestamp.
+func formatTimestamp(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	millis := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d,%03d",
+		millis/3600000, millis/60000%60, millis/1000%60, millis%1000)
+}
+
+// srtDuration returns the end time of the last cue in the SRT content.
+func srtDuration(content string) time.Duration {
+	var end time.Duration
+	for _, cue := range parseSRT(content) {
+		if cueEnd, err := parseTimestamp(cue.EndTime); err == nil && cueEnd > end {
+			end = cueEnd
+		}
+	}
+	return end
+}
+
+// formatSRT writes cues as SRT content. Speakers are kept as voice tags so
+// that they survive the queue; exports write them in their own formats.
+func formatSRT(cues []SRTCue) string {
+	var b strings.Builder
+	for _, cue := range cues {
+		text := strings.TrimSpace(cue.Text)
+		if cue.Speaker != "" {
+			text = "<v " + cue.Speaker + ">" + text
+		}
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", cue.SlNo, cue.StartTime, cue.EndTime, text)
+	}
+	return b.String()
+}
@@ -7,24 +7,77 @@ import (
 	"fmt"
 	"infinity-subtitle/backend/database"
 	"infinity-subtitle/backend/logger"
-	"os"
-	"path/filepath"
-	"strconv"
-
-	// "strconv"
+	"sort"
 	"strings"
 	"time"
 )
 
 type Subtitle struct {
-	ID        int               `json:"id"`
-	MovieID   int               `json:"movie_id"`
-	SlNo      int               `json:"sl_no"`
-	StartTime string            `json:"start_time"`
-	EndTime   string            `json:"end_time"`
-	Content   map[string]string `json:"content"`
-	CreatedAt time.Time         `json:"created_at"`
-	UpdatedAt time.Time         `json:"updated_at"`
+	ID        int                     `json:"id"`
+	MovieID   int                     `json:"movie_id"`
+	SlNo      int                     `json:"sl_no"`
+	StartTime string                  `json:"start_time"`
+	EndTime   string                  `json:"end_time"`
+	Content   map[string]string       `json:"content"`
+	Quality   map[string]QualityScore `json:"quality"`
+	// Speaker says who speaks the cue, from diarization or assigned by hand
+	Speaker string `json:"speaker"`
+	// TranslationChain lists, per language, the languages a translation went
+	// through, starting with the source
+	TranslationChain map[string][]string `json:"translation_chain"`
+	// Candidates holds alternative translations per language
+	Candidates map[string][]TranslationCandidate `json:"candidates"`
+	CreatedAt  time.Time                         `json:"created_at"`
+	UpdatedAt  time.Time                         `json:"updated_at"`
+}
+
+// subtitleColumns is the column list read by scanSubtitle.
+const subtitleColumns = "id, movie_id, sl_no, start_time, end_time, content, quality, translation_chain, candidates, " +
+	"speaker, created_at, updated_at"
+
+// scanSubtitle reads a row selected with subtitleColumns.
+func scanSubtitle(row interface{ Scan(dest ...any) error }) (Subtitle, error) {
+	var subtitle Subtitle
+	var contentJson []byte
+	var qualityJson []byte
+	var chainJson []byte
+	var candidatesJson []byte
+	err := row.Scan(&subtitle.ID, &subtitle.MovieID, &subtitle.SlNo, &subtitle.StartTime, &subtitle.EndTime,
+		&contentJson, &qualityJson, &chainJson, &candidatesJson, &subtitle.Speaker, &subtitle.CreatedAt, &subtitle.UpdatedAt)
+	if err != nil {
+		return subtitle, fmt.Errorf("failed to scan subtitle: %w", err)
+	}
+
+	err = json.Unmarshal(contentJson, &subtitle.Content)
+	if err != nil {
+		return subtitle, fmt.Errorf("failed to unmarshal content: %w", err)
+	}
+
+	subtitle.Quality = make(map[string]QualityScore)
+	if len(qualityJson) > 0 {
+		err = json.Unmarshal(qualityJson, &subtitle.Quality)
+		if err != nil {
+			return subtitle, fmt.Errorf("failed to unmarshal quality: %w", err)
+		}
+	}
+
+	subtitle.TranslationChain = make(map[string][]string)
+	if len(chainJson) > 0 {
+		err = json.Unmarshal(chainJson, &subtitle.TranslationChain)
+		if err != nil {
+			return subtitle, fmt.Errorf("failed to unmarshal translation chain: %w", err)
+		}
+	}
+
+	subtitle.Candidates = make(map[string][]TranslationCandidate)
+	if len(candidatesJson) > 0 {
+		err = json.Unmarshal(candidatesJson, &subtitle.Candidates)
+		if err != nil {
+			return subtitle, fmt.Errorf("failed to unmarshal candidates: %w", err)
+		}
+	}
+
+	return subtitle, nil
 }
 
 type SubtitleResponse struct {
@@ -57,7 +110,7 @@ func (s Subtitle) GetSubtitlesByMovieID(movieID int, pagination Pagination) (Sub
 
 	// Get paginated subtitles
 	rows, err := db.Query(`
-		SELECT id, movie_id, sl_no, start_time, end_time, content, created_at, updated_at 
+		SELECT `+subtitleColumns+`
 		FROM subtitles 
 		WHERE movie_id = ? 
 		ORDER BY sl_no ASC
@@ -69,17 +122,10 @@ func (s Subtitle) GetSubtitlesByMovieID(movieID int, pagination Pagination) (Sub
 	defer rows.Close()
 
 	for rows.Next() {
-		var subtitle Subtitle
-		var contentJson []byte
-		err := rows.Scan(&subtitle.ID, &subtitle.MovieID, &subtitle.SlNo, &subtitle.StartTime, &subtitle.EndTime, &contentJson, &subtitle.CreatedAt, &subtitle.UpdatedAt)
+		subtitle, err := scanSubtitle(rows)
 		if err != nil {
 			return response, err
 		}
-
-		err = json.Unmarshal(contentJson, &subtitle.Content)
-		if err != nil {
-			return response, fmt.Errorf("failed to unmarshal content: %w", err)
-		}
 		subtitles = append(subtitles, subtitle)
 	}
 
@@ -101,6 +147,40 @@ func (s Subtitle) GetSubtitlesByMovieID(movieID int, pagination Pagination) (Sub
 	return response, nil
 }
 
+// getAllSubtitles returns every subtitle of a movie in order.
+func getAllSubtitles(movieID int) ([]Subtitle, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	rows, err := db.Query(`
+		SELECT `+subtitleColumns+`
+		FROM subtitles 
+		WHERE movie_id = ? 
+		ORDER BY sl_no ASC
+	`, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subtitles: %w", err)
+	}
+	defer rows.Close()
+
+	var subtitles []Subtitle
+	for rows.Next() {
+		subtitle, err := scanSubtitle(rows)
+		if err != nil {
+			return nil, err
+		}
+		subtitles = append(subtitles, subtitle)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subtitles: %w", err)
+	}
+
+	return subtitles, nil
+}
+
 func (s Subtitle) UpdateSubtitle(subtitle Subtitle) error {
 	db := database.GetDB()
 	if db == nil {
@@ -111,8 +191,45 @@ func (s Subtitle) UpdateSubtitle(subtitle Subtitle) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal content: %w", err)
 	}
+	speaker, err := normalizeSpeaker(subtitle.Speaker)
+	if err != nil {
+		return err
+	}
 
-	_, err = db.Exec("UPDATE subtitles SET content = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", contentJson, subtitle.ID)
+	// Quality scores describe the text they were given, so edited languages lose theirs
+	var previousJson []byte
+	err = db.QueryRow("SELECT content FROM subtitles WHERE id = ?", subtitle.ID).Scan(&previousJson)
+	if err != nil {
+		return fmt.Errorf("failed to get subtitle: %w", err)
+	}
+	previous := make(map[string]string)
+	if len(previousJson) > 0 {
+		if err := json.Unmarshal(previousJson, &previous); err != nil {
+			return fmt.Errorf("failed to unmarshal content: %w", err)
+		}
+	}
+	stale := []any{}
+	for lang, text := range previous {
+		if subtitle.Content[lang] != text {
+			stale = append(stale, "$."+lang)
+		}
+	}
+	for lang := range subtitle.Content {
+		if _, ok := previous[lang]; !ok {
+			stale = append(stale, "$."+lang)
+		}
+	}
+	quality := "quality"
+	if len(stale) > 0 {
+		quality = "json_remove(quality" + strings.Repeat(", ?", len(stale)) + ")"
+	}
+
+	args := append([]any{contentJson, speaker}, stale...)
+	_, err = db.Exec(`
+		UPDATE subtitles
+		SET content = ?, speaker = ?, quality = `+quality+`, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, append(args, subtitle.ID)...)
 	if err != nil {
 		return fmt.Errorf("failed to update subtitle: %w", err)
 	}
@@ -131,71 +248,25 @@ func (s Subtitle) ImportFromSRTFile(movie Movie, fileContent string) error {
 		return errors.New("database connection is nil")
 	}
 
-	fileContent = strings.TrimSpace(fileContent)
-	lines := strings.Split(fileContent, "\n")
 	var subtitles []Subtitle
 
-	contents := make(map[string]string)
-
-	for key := range movie.Languages {
-		contents[key] = ""
-	}
-
-	subtitle := &Subtitle{
-		SlNo:      0,
-		MovieID:   movie.ID,
-		StartTime: "",
-		EndTime:   "",
-		Content:   contents,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-
-	for i, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		slNo, err := strconv.Atoi(line)
-		if err == nil {
-			subtitle.SlNo = slNo
-			continue
-		}
-
-		if strings.Contains(line, "-->") {
-			parts := strings.Split(line, "-->")
-			if len(parts) == 2 {
-				subtitle.StartTime = strings.TrimSpace(parts[0])
-				subtitle.EndTime = strings.TrimSpace(parts[1])
-				continue
-			}
-		}
-
-		subtitle.Content[movie.DefaultLanguage] += line + "\n"
-
-		nextLine := ""
-		if i+1 < len(lines) {
-			nextLine = strings.TrimSpace(lines[i+1])
-		}
-
-		if nextLine == "" {
-			subtitles = append(subtitles, *subtitle)
-			subtitle = &Subtitle{
-				SlNo:      0,
-				MovieID:   movie.ID,
-				StartTime: "",
-				EndTime:   "",
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
-			}
-
-			contents := make(map[string]string)
-			for key := range movie.Languages {
-				contents[key] = ""
-			}
-			subtitle.Content = contents
+	for _, cue := range parseSRT(fileContent) {
+		contents := make(map[string]string)
+		for key := range movie.Languages {
+			contents[key] = ""
 		}
+		contents[movie.DefaultLanguage] = cue.Text
+
+		subtitles = append(subtitles, Subtitle{
+			SlNo:      cue.SlNo,
+			MovieID:   movie.ID,
+			StartTime: cue.StartTime,
+			EndTime:   cue.EndTime,
+			Content:   contents,
+			Speaker:   sanitizeSpeaker(cue.Speaker),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		})
 	}
 
 	// delete all subtitles for the movie
@@ -214,8 +285,8 @@ func (s Subtitle) ImportFromSRTFile(movie Movie, fileContent string) error {
 
 	// Prepare the bulk insert statement
 	stmt, err := tx.Prepare(`
-		INSERT INTO subtitles (movie_id, sl_no, start_time, end_time, content, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		INSERT INTO subtitles (movie_id, sl_no, start_time, end_time, content, speaker, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
@@ -235,6 +306,7 @@ func (s Subtitle) ImportFromSRTFile(movie Movie, fileContent string) error {
 			subtitle.StartTime,
 			subtitle.EndTime,
 			contentJson,
+			subtitle.Speaker,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to execute statement: %w", err)
@@ -249,97 +321,257 @@ func (s Subtitle) ImportFromSRTFile(movie Movie, fileContent string) error {
 	return nil
 }
 
-func (s Subtitle) TranslateSubtitles(movieId int, sourceLanguage string, targetLanguage string) ([]string, error) {
-	db := database.GetDB()
-	if db == nil {
-		return nil, errors.New("database connection is nil")
+// TranslateSelectionRequest selects cues to translate on demand. Cues are
+// selected by an inclusive range of serial numbers, by id, or both.
+type TranslateSelectionRequest struct {
+	MovieID        int    `json:"movie_id"`
+	SourceLanguage string `json:"source_language"`
+	TargetLanguage string `json:"target_language"`
+	FromSlNo       int    `json:"from_sl_no"`
+	// ToSlNo of 0 selects up to the last cue
+	ToSlNo int   `json:"to_sl_no"`
+	IDs    []int `json:"ids"`
+	// Overwrite re-translates cues that already have a translation
+	Overwrite   bool   `json:"overwrite"`
+	Instruction string `json:"instruction"`
+}
+
+// translationSelection narrows which subtitles are translated. The zero
+// value selects every subtitle without a translation.
+type translationSelection struct {
+	ids         map[int]bool
+	fromSlNo    int
+	toSlNo      int
+	overwrite   bool
+	instruction string
+}
+
+func (sel translationSelection) includes(subtitle Subtitle) bool {
+	if sel.ids == nil && sel.fromSlNo == 0 && sel.toSlNo == 0 {
+		return true
+	}
+	if sel.ids[subtitle.ID] {
+		return true
+	}
+	if sel.fromSlNo == 0 && sel.toSlNo == 0 {
+		return false
 	}
+	return subtitle.SlNo >= sel.fromSlNo && (sel.toSlNo == 0 || subtitle.SlNo <= sel.toSlNo)
+}
+
+// TranslateSubtitles runs as a job that reports progress and can be stopped
+// with CancelJob.
+func (s Subtitle) TranslateSubtitles(movieId int, sourceLanguage string, targetLanguage string) (TranslationReport, error) {
+	job, ctx := startJob(context.Background(), JobKindTranslation, movieId, 0)
+	defer job.finish()
+	return translateSubtitles(ctx, 0, movieId, sourceLanguage, targetLanguage, translationSelection{})
+}
 
+// TranslateSelection translates the selected cues, optionally replacing
+// existing translations. Replaced texts are kept in the subtitle history.
+func (s Subtitle) TranslateSelection(req TranslateSelectionRequest) (TranslationReport, error) {
+	if req.SourceLanguage == "" || req.TargetLanguage == "" {
+		return newTranslationReport(req.TargetLanguage), errors.New("source and target languages are required")
+	}
+	if req.SourceLanguage == req.TargetLanguage {
+		return newTranslationReport(req.TargetLanguage), errors.New("source and target languages cannot be the same")
+	}
+	if len(req.IDs) == 0 && req.FromSlNo <= 0 && req.ToSlNo <= 0 {
+		return newTranslationReport(req.TargetLanguage), errors.New("select a range or at least one subtitle")
+	}
+	if req.ToSlNo > 0 && req.FromSlNo > req.ToSlNo {
+		return newTranslationReport(req.TargetLanguage), errors.New("range start is after its end")
+	}
+
+	sel := translationSelection{
+		ids:         make(map[int]bool, len(req.IDs)),
+		fromSlNo:    max(req.FromSlNo, 0),
+		toSlNo:      max(req.ToSlNo, 0),
+		overwrite:   req.Overwrite,
+		instruction: strings.TrimSpace(req.Instruction),
+	}
+	for _, id := range req.IDs {
+		sel.ids[id] = true
+	}
+
+	job, ctx := startJob(context.Background(), JobKindTranslation, req.MovieID, 0)
+	defer job.finish()
+	return translateSubtitles(ctx, 0, req.MovieID, req.SourceLanguage, req.TargetLanguage, sel)
+}
+
+// translateSubtitles fills the empty target language content of the selected
+// subtitles of a movie. The queue id, if any, is used to attribute usage. When
+// the movie has a pivot language, the pivot is filled from the source first
+// and the target is translated from the pivot.
+func translateSubtitles(ctx context.Context, queueID int, movieId int, sourceLanguage string,
+	targetLanguage string, sel translationSelection) (TranslationReport, error) {
 	movie := NewMovie()
 	movie, err := movie.GetMovieByID(movieId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get movie: %w", err)
+		return newTranslationReport(targetLanguage), fmt.Errorf("failed to get movie: %w", err)
 	}
 
-	translationService, err := NewTranslationService()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create translation service: %w", err)
+	pivotLanguage := movie.PivotLanguage
+	if pivotLanguage == "" || pivotLanguage == sourceLanguage || pivotLanguage == targetLanguage {
+		return fillTranslations(ctx, queueID, movie, sourceLanguage, targetLanguage,
+			[]string{sourceLanguage, targetLanguage}, sel)
 	}
-	defer translationService.Close()
 
-	ctx := context.Background()
+	// Existing pivot texts are reused as they are, only the target follows
+	// the overwrite flag and instruction
+	pivotSel := sel
+	pivotSel.overwrite = false
+	pivotSel.instruction = ""
+	pivotReport, err := fillTranslations(ctx, queueID, movie, sourceLanguage, pivotLanguage,
+		[]string{sourceLanguage, pivotLanguage}, pivotSel)
+	if err != nil {
+		return pivotReport, fmt.Errorf("failed to translate to pivot language %s: %w", pivotLanguage, err)
+	}
+	if pivotReport.Cancelled {
+		report := newTranslationReport(targetLanguage)
+		report.Cancelled = true
+		report.Errors = pivotReport.Errors
+		return report, nil
+	}
 
-	// Get all subtitles for the movie
-	rows, err := db.Query(`
-		SELECT id, movie_id, content
-		FROM subtitles 
-		WHERE movie_id = ? 
-		ORDER BY sl_no ASC
-	`, movieId)
+	report, err := fillTranslations(ctx, queueID, movie, pivotLanguage, targetLanguage,
+		[]string{sourceLanguage, pivotLanguage, targetLanguage}, sel)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get subtitles: %w", err)
+		return report, err
 	}
-	defer rows.Close()
+	report.Errors = append(report.Errors, pivotReport.Errors...)
 
-	var subtitles []Subtitle
-	for rows.Next() {
-		var subtitle Subtitle
-		var contentJson []byte
-		err := rows.Scan(&subtitle.ID, &subtitle.MovieID, &contentJson)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan subtitle: %w", err)
+	// Subtitles without a pivot translation were skipped by the second pass
+	subtitles, err := getAllSubtitles(movieId)
+	if err != nil {
+		return report, err
+	}
+	untranslated := make(map[int]bool, len(report.UntranslatedIDs))
+	for _, id := range report.UntranslatedIDs {
+		untranslated[id] = true
+	}
+	for _, subtitle := range subtitles {
+		if !sel.includes(subtitle) || untranslated[subtitle.ID] {
+			continue
 		}
-
-		err = json.Unmarshal(contentJson, &subtitle.Content)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal content: %w", err)
+		// A selected subtitle was skipped if it has no pivot text
+		skipped := subtitle.Content[pivotLanguage] == "" && (sel.overwrite || subtitle.Content[targetLanguage] == "")
+		if subtitle.Content[sourceLanguage] != "" && skipped {
+			report.UntranslatedIDs = append(report.UntranslatedIDs, subtitle.ID)
 		}
-		subtitles = append(subtitles, subtitle)
 	}
+	sort.Ints(report.UntranslatedIDs)
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating subtitles: %w", err)
+	return report, nil
+}
+
+func newTranslationReport(language string) TranslationReport {
+	return TranslationReport{
+		Language:        language,
+		UntranslatedIDs: []int{},
+		Errors:          []BatchError{},
+		OverLimitIDs:    []int{},
+	}
+}
+
+// fillTranslations translates the selected subtitles that have content in the
+// source language but none in the target language, or any target content if
+// the selection overwrites, and records the chain of languages each
+// translation went through.
+func fillTranslations(ctx context.Context, queueID int, movie *Movie, sourceLanguage string,
+	targetLanguage string, chain []string, sel translationSelection) (TranslationReport, error) {
+	report := newTranslationReport(targetLanguage)
+
+	db := database.GetDB()
+	if db == nil {
+		return report, errors.New("database connection is nil")
+	}
+
+	chainJson, err := json.Marshal(chain)
+	if err != nil {
+		return report, fmt.Errorf("failed to marshal translation chain: %w", err)
+	}
+
+	translationService, err := NewTranslationService()
+	if err != nil {
+		return report, fmt.Errorf("failed to create translation service: %w", err)
+	}
+	defer translationService.Close()
+	translationService.usage = usageKey{MovieID: movie.ID, QueueID: queueID, Language: targetLanguage}
+	if constraints := getSubtitleConstraints(); constraints.enabled() {
+		translationService.constraints = &constraints
+	}
+	translationService.instruction = sel.instruction
+	// Overwriting asks for a new translation, not the one stored before
+	translationService.refreshCache = sel.overwrite
+
+	// Get all subtitles for the movie
+	subtitles, err := getAllSubtitles(movie.ID)
+	if err != nil {
+		return report, err
 	}
 
 	if len(subtitles) == 0 {
-		return nil, nil
+		return report, nil
 	}
 
 	var textsToTranslate []TextToTranslate
+	previous := make(map[int]string)
 
 	// Collect unique texts for translation
 
 	for _, subtitle := range subtitles {
+		if !sel.includes(subtitle) {
+			continue
+		}
+
 		sourceText := subtitle.Content[sourceLanguage]
 		if sourceText == "" {
 			continue
 		}
 
 		targetText := subtitle.Content[targetLanguage]
-		if targetText != "" {
+		if targetText != "" && !sel.overwrite {
 			continue
 		}
+		previous[subtitle.ID] = targetText
 
-		textsToTranslate = append(textsToTranslate, TextToTranslate{
+		text := TextToTranslate{
 			ID:          subtitle.ID,
 			SourceText:  sourceText,
 			Translation: "",
-		})
+		}
+		if translationService.constraints != nil {
+			text.MaxChars = translationService.constraints.maxChars(subtitle.StartTime, subtitle.EndTime)
+		}
+		textsToTranslate = append(textsToTranslate, text)
+	}
+
+	if job := jobFromContext(ctx); job != nil {
+		translationService.progress = func(done int, total int) {
+			job.reportProgress(targetLanguage, done, total)
+		}
+		job.reportProgress(targetLanguage, 0, len(textsToTranslate))
 	}
 
 	// Process translations in parallel
 	sourceLangFullText := movie.Languages[sourceLanguage]
 	targetLangFullText := movie.Languages[targetLanguage]
-	translations := translationService.processBatch(ctx, textsToTranslate, sourceLangFullText, targetLangFullText)
+	translations, batchErrors := translationService.processBatch(ctx, textsToTranslate, sourceLangFullText, targetLangFullText)
+	report.Errors = append(report.Errors, batchErrors...)
+	report.CacheHits = translationService.cacheHits
+	for _, batchError := range batchErrors {
+		report.UntranslatedIDs = append(report.UntranslatedIDs, batchError.IDs...)
+	}
 
-	// Update subtitles with translations
+	// Update subtitles with translations. Batches finished before a
+	// cancellation are saved as well.
+	report.Cancelled = ctx.Err() != nil
 	tx, err := db.Begin()
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return report, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
-	var emptyTranslations []string
-
 	for _, translation := range translations {
 		value := translation.SourceText
 		if value == "" {
@@ -347,118 +579,51 @@ func (s Subtitle) TranslateSubtitles(movieId int, sourceLanguage string, targetL
 		}
 		translated := translation.Translation
 		if translated == "" {
-			emptyTranslations = append(emptyTranslations, translation.SourceText)
+			report.UntranslatedIDs = append(report.UntranslatedIDs, translation.ID)
 			continue
 		}
+		if previousText := previous[translation.ID]; previousText != "" && previousText != translated {
+			err = recordSubtitleHistory(tx, SubtitleHistory{
+				SubtitleID:   translation.ID,
+				Language:     targetLanguage,
+				PreviousText: previousText,
+				NewText:      translated,
+				Reason:       SubtitleHistoryReasonRetranslation,
+				Instruction:  sel.instruction,
+			})
+			if err != nil {
+				tx.Rollback()
+				return report, err
+			}
+		}
 		_, err = tx.Exec(`
 			UPDATE subtitles 
 			SET content = json_set(content, '$.' || ?, ?),
+				translation_chain = json_set(COALESCE(translation_chain, '{}'), '$.' || ?, json(?)),
+				quality = json_remove(quality, '$.' || ?),
 				updated_at = CURRENT_TIMESTAMP
 			WHERE id = ?
-		`, targetLanguage, translated, translation.ID)
+		`, targetLanguage, translated, targetLanguage, string(chainJson), targetLanguage, translation.ID)
 		if err != nil {
 			tx.Rollback()
-			return nil, fmt.Errorf("failed to update subtitle: %w", err)
+			return report, fmt.Errorf("failed to update subtitle: %w", err)
+		}
+		report.Translated++
+		if translationService.constraints != nil && !translationService.constraints.fits(translated, translation.MaxChars) {
+			report.OverLimitIDs = append(report.OverLimitIDs, translation.ID)
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		return report, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return emptyTranslations, nil
+	sort.Ints(report.UntranslatedIDs)
+	sort.Ints(report.OverLimitIDs)
+
+	return report, nil
 }
 
 func (s Subtitle) ExportSubtitle(movieId int, language string) (ExportResponse, error) {
-	db := database.GetDB()
-	if db == nil {
-		return ExportResponse{}, errors.New("database connection is nil")
-	}
-
-	// Get movie details
-	movie := NewMovie()
-	movie, err := movie.GetMovieByID(movieId)
-	if err != nil {
-		return ExportResponse{}, fmt.Errorf("failed to get movie: %w", err)
-	}
-
-	// Get all subtitles for the movie
-	rows, err := db.Query(`
-		SELECT id, movie_id, sl_no, start_time, end_time, content, created_at, updated_at 
-		FROM subtitles 
-		WHERE movie_id = ? 
-		ORDER BY sl_no ASC
-	`, movieId)
-	if err != nil {
-		return ExportResponse{}, fmt.Errorf("failed to get subtitles: %w", err)
-	}
-	defer rows.Close()
-
-	var subtitles []Subtitle
-	for rows.Next() {
-		var subtitle Subtitle
-		var contentJson []byte
-		err := rows.Scan(&subtitle.ID, &subtitle.MovieID, &subtitle.SlNo, &subtitle.StartTime, &subtitle.EndTime, &contentJson, &subtitle.CreatedAt, &subtitle.UpdatedAt)
-		if err != nil {
-			return ExportResponse{}, fmt.Errorf("failed to scan subtitle: %w", err)
-		}
-
-		err = json.Unmarshal(contentJson, &subtitle.Content)
-		if err != nil {
-			return ExportResponse{}, fmt.Errorf("failed to unmarshal content: %w", err)
-		}
-		subtitles = append(subtitles, subtitle)
-	}
-
-	if err = rows.Err(); err != nil {
-		return ExportResponse{}, fmt.Errorf("error iterating subtitles: %w", err)
-	}
-
-	// Create subtitles directory if it doesn't exist
-	subtitlesDir := "subtitles"
-	if err := os.MkdirAll(subtitlesDir, 0755); err != nil {
-		return ExportResponse{}, fmt.Errorf("failed to create subtitles directory: %w", err)
-	}
-
-	// Create movie directory
-	movieDir := filepath.Join(subtitlesDir, movie.Title)
-	if err := os.MkdirAll(movieDir, 0755); err != nil {
-		return ExportResponse{}, fmt.Errorf("failed to create movie directory: %w", err)
-	}
-
-	// Create SRT file
-	fileName := fmt.Sprintf("%s - %s.srt", movie.Title, movie.Languages[language])
-	filePath := filepath.Join(movieDir, fileName)
-	file, err := os.Create(filePath)
-	if err != nil {
-		return ExportResponse{}, fmt.Errorf("failed to create export file: %w", err)
-	}
-	defer file.Close()
-
-	// Write subtitles in SRT format
-	for _, subtitle := range subtitles {
-		content := subtitle.Content[language]
-		if content == "" {
-			continue
-		}
-
-		// Write subtitle number
-		fmt.Fprintf(file, "%d\n", subtitle.SlNo)
-
-		// Write time
-		fmt.Fprintf(file, "%s --> %s\n", subtitle.StartTime, subtitle.EndTime)
-
-		// Write content
-		fmt.Fprintf(file, "%s\n\n", content)
-	}
-
-	// Get absolute path
-	absPath, err := filepath.Abs(filePath)
-	if err != nil {
-		return ExportResponse{}, fmt.Errorf("failed to get absolute path: %w", err)
-	}
-
-	return ExportResponse{
-		FilePath: absPath,
-	}, nil
+	return s.ExportSubtitleAs(movieId, language, ExportFormatSRT)
 }
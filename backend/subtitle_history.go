@@ -0,0 +1,66 @@
+package backend
+
+import (
+	"database/sql"
+	"fmt"
+	"infinity-subtitle/backend/database"
+	"time"
+)
+
+const (
+	SubtitleHistoryReasonRetranslation = "retranslation"
+)
+
+// SubtitleHistory is a text of a subtitle that was replaced.
+type SubtitleHistory struct {
+	ID           int    `json:"id"`
+	SubtitleID   int    `json:"subtitle_id"`
+	Language     string `json:"language"`
+	PreviousText string `json:"previous_text"`
+	NewText      string `json:"new_text"`
+	Reason       string `json:"reason"`
+	// Instruction is the editor's instruction the new text was made with, if any
+	Instruction string    `json:"instruction"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// recordSubtitleHistory stores a replaced text within the transaction that
+// replaces it.
+func recordSubtitleHistory(tx *sql.Tx, entry SubtitleHistory) error {
+	_, err := tx.Exec(`
+		INSERT INTO subtitle_history (subtitle_id, language, previous_text, new_text, reason, instruction)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, entry.SubtitleID, entry.Language, entry.PreviousText, entry.NewText, entry.Reason, entry.Instruction)
+	if err != nil {
+		return fmt.Errorf("failed to record subtitle history: %w", err)
+	}
+	return nil
+}
+
+// GetSubtitleHistory returns the replaced texts of a subtitle, newest first.
+func (s Subtitle) GetSubtitleHistory(subtitleID int) ([]SubtitleHistory, error) {
+	db := database.GetDB()
+	rows, err := db.Query(`
+		SELECT id, subtitle_id, language, previous_text, new_text, reason, instruction, created_at
+		FROM subtitle_history
+		WHERE subtitle_id = ?
+		ORDER BY id DESC
+	`, subtitleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subtitle history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []SubtitleHistory{}
+	for rows.Next() {
+		var entry SubtitleHistory
+		err := rows.Scan(&entry.ID, &entry.SubtitleID, &entry.Language, &entry.PreviousText, &entry.NewText,
+			&entry.Reason, &entry.Instruction, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subtitle history: %w", err)
+		}
+		history = append(history, entry)
+	}
+
+	return history, rows.Err()
+}
@@ -0,0 +1,338 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	defaultMaxSyncOffset = 5 * time.Minute
+	maxSyncOffsetLimit   = 30 * time.Minute
+	// syncCoarseStep is the step, in energy frames, of the global offset
+	// search; the best offset is then refined frame by frame
+	syncCoarseStep = 5
+	// syncWindow is the stretch of cues each drift anchor is fitted to
+	syncWindow = 2 * time.Minute
+	// syncLocalRange is how far a window may move from the global fit
+	syncLocalRange = 10 * time.Second
+	// syncMinCueTime is the cue time a window needs for its anchor to be trusted
+	syncMinCueTime = 20 * time.Second
+	// syncMinSlope keeps neighbouring anchors from reversing the order of cues
+	syncMinSlope = 0.5
+)
+
+// syncScales are the speed changes of frame rate conversions between
+// releases, tried for the global fit.
+var syncScales = []float64{1, 25 / 23.976, 23.976 / 25, 24 / 23.976, 23.976 / 24, 25.0 / 24, 24.0 / 25}
+
+// AutoSyncRequest asks to fit the subtitles of a movie to its audio.
+type AutoSyncRequest struct {
+	MovieID int `json:"movie_id"`
+	// MaxOffsetSeconds bounds the global offset searched, 300 if zero
+	MaxOffsetSeconds int `json:"max_offset_seconds"`
+	// DryRun reports the changes without saving them
+	DryRun bool `json:"dry_run"`
+}
+
+// SyncAnchor is a local correction on top of the global fit, at a time of
+// the synced subtitles. Between anchors the correction is interpolated.
+type SyncAnchor struct {
+	TimeMs   int64 `json:"time_ms"`
+	OffsetMs int64 `json:"offset_ms"`
+}
+
+// SyncReport is the fit found by AutoSync and the cues it moved.
+type SyncReport struct {
+	// OffsetMs and Scale are the global fit, new time = old time * scale + offset
+	OffsetMs int64          `json:"offset_ms"`
+	Scale    float64        `json:"scale"`
+	Anchors  []SyncAnchor   `json:"anchors"`
+	Checked  int            `json:"checked"`
+	Changes  []TimingChange `json:"changes"`
+}
+
+// speechActivity scores spans of a recording: +1 for every energy frame of
+// speech and -1 for every frame of silence or beyond the recording.
+type speechActivity struct {
+	prefix []int
+}
+
+func newSpeechActivity(speech []speechSegment, total time.Duration) speechActivity {
+	frames := int(total / energyFrame)
+	for _, segment := range speech {
+		frames = max(frames, int(segment.End/energyFrame))
+	}
+
+	values := make([]int, frames)
+	for i := range values {
+		values[i] = -1
+	}
+	for _, segment := range speech {
+		for i := int(segment.Start / energyFrame); i < int(segment.End/energyFrame); i++ {
+			values[i] = 1
+		}
+	}
+
+	prefix := make([]int, frames+1)
+	for i, v := range values {
+		prefix[i+1] = prefix[i] + v
+	}
+	return speechActivity{prefix: prefix}
+}
+
+// score sums the frames from up to to.
+func (a speechActivity) score(from int, to int) int {
+	if to <= from {
+		return 0
+	}
+	n := len(a.prefix) - 1
+	s := 0
+	if from < 0 {
+		s -= min(to, 0) - from
+		from = 0
+	}
+	if to > n {
+		s -= to - max(from, n)
+		to = n
+	}
+	if from < to {
+		s += a.prefix[to] - a.prefix[from]
+	}
+	return s
+}
+
+// cueFrames are the start and end frames of cues.
+type cueFrames struct {
+	start, end []int
+}
+
+func scaledCueFrames(cues []timedCue, scale float64) cueFrames {
+	frames := cueFrames{start: make([]int, len(cues)), end: make([]int, len(cues))}
+	for i, cue := range cues {
+		frames.start[i] = int(math.Round(float64(cue.start) * scale / float64(energyFrame)))
+		frames.end[i] = int(math.Round(float64(cue.end) * scale / float64(energyFrame)))
+	}
+	return frames
+}
+
+// score rates how well the cues from first up to last, shifted by offset
+// frames, cover speech and leave silence uncovered.
+func (f cueFrames) score(activity speechActivity, first int, last int, offset int) int {
+	s := 0
+	for i := first; i < last; i++ {
+		s += activity.score(f.start[i]+offset, f.end[i]+offset)
+	}
+	return s
+}
+
+// bestOffset searches offsets from -limit to limit frames, every step frames
+// and then frame by frame around the best, and returns the best one.
+func (f cueFrames) bestOffset(activity speechActivity, first int, last int, limit int, step int) (int, int) {
+	best, bestScore := 0, f.score(activity, first, last, 0)
+	search := func(from, to, step int) {
+		for offset := from; offset <= to; offset += step {
+			// Ties go to the smaller shift
+			score := f.score(activity, first, last, offset)
+			if score > bestScore || (score == bestScore && abs(offset) < abs(best)) {
+				best, bestScore = offset, score
+			}
+		}
+	}
+	search(-limit, limit, step)
+	if step > 1 {
+		search(best-step+1, best+step-1, 1)
+	}
+	return best, bestScore
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// syncFit maps cue times onto the audio: a global scale and offset, and
+// local corrections interpolated between anchors.
+type syncFit struct {
+	scale   float64
+	offset  time.Duration
+	anchors []SyncAnchor
+}
+
+// apply maps a time of the cues to a time of the audio.
+func (fit syncFit) apply(t time.Duration) time.Duration {
+	u := time.Duration(float64(t)*fit.scale) + fit.offset
+	return u + fit.correction(u)
+}
+
+// correction interpolates the anchors at u, a time after the global fit.
+func (fit syncFit) correction(u time.Duration) time.Duration {
+	if len(fit.anchors) == 0 {
+		return 0
+	}
+	ms := u.Milliseconds()
+	first, last := fit.anchors[0], fit.anchors[len(fit.anchors)-1]
+	if ms <= first.TimeMs {
+		return time.Duration(first.OffsetMs) * time.Millisecond
+	}
+	if ms >= last.TimeMs {
+		return time.Duration(last.OffsetMs) * time.Millisecond
+	}
+	for i := 1; i < len(fit.anchors); i++ {
+		a, b := fit.anchors[i-1], fit.anchors[i]
+		if ms <= b.TimeMs {
+			ratio := float64(ms-a.TimeMs) / float64(b.TimeMs-a.TimeMs)
+			return time.Duration(float64(a.OffsetMs)+ratio*float64(b.OffsetMs-a.OffsetMs)) * time.Millisecond
+		}
+	}
+	return 0
+}
+
+// fitSync finds the scale and offset that best line cues up with speech,
+// then fits a local offset to each syncWindow of cues to follow drift and
+// scenes cut or added in another release.
+func fitSync(cues []timedCue, speech []speechSegment, total time.Duration, maxOffset time.Duration) syncFit {
+	activity := newSpeechActivity(speech, total)
+	limit := int(maxOffset / energyFrame)
+
+	fit := syncFit{scale: 1}
+	var fitFrames cueFrames
+	bestScore := math.MinInt
+	for _, scale := range syncScales {
+		frames := scaledCueFrames(cues, scale)
+		offset, score := frames.bestOffset(activity, 0, len(cues), limit, syncCoarseStep)
+		if score > bestScore {
+			bestScore = score
+			fit.scale, fit.offset = scale, time.Duration(offset)*energyFrame
+			fitFrames = frames
+		}
+	}
+
+	// Local offsets are searched around the global fit
+	globalOffset := int(fit.offset / energyFrame)
+	for i := range cues {
+		fitFrames.start[i] += globalOffset
+		fitFrames.end[i] += globalOffset
+	}
+	localLimit := int(syncLocalRange / energyFrame)
+	windowFrames := int(syncWindow / energyFrame)
+	for first := 0; first < len(cues); {
+		window := fitFrames.start[first] / windowFrames
+		last, cueFramesTotal, centre := first, 0, 0
+		for last < len(cues) && fitFrames.start[last]/windowFrames == window {
+			cueFramesTotal += fitFrames.end[last] - fitFrames.start[last]
+			centre += fitFrames.start[last]
+			last++
+		}
+
+		if time.Duration(cueFramesTotal)*energyFrame >= syncMinCueTime {
+			offset, score := fitFrames.bestOffset(activity, first, last, localLimit, 1)
+			anchor := SyncAnchor{
+				TimeMs:   (time.Duration(centre/(last-first)) * energyFrame).Milliseconds(),
+				OffsetMs: (time.Duration(offset) * energyFrame).Milliseconds(),
+			}
+			if score > 0 && keepsOrder(fit.anchors, anchor) {
+				fit.anchors = append(fit.anchors, anchor)
+			}
+		}
+		first = last
+	}
+
+	return fit
+}
+
+// keepsOrder reports whether an anchor can follow the last one without
+// squeezing the time between them below syncMinSlope.
+func keepsOrder(anchors []SyncAnchor, anchor SyncAnchor) bool {
+	if len(anchors) == 0 {
+		return true
+	}
+	prev := anchors[len(anchors)-1]
+	span := float64(anchor.TimeMs - prev.TimeMs)
+	return span > 0 && span+float64(anchor.OffsetMs-prev.OffsetMs) >= syncMinSlope*span
+}
+
+// AutoSync fits the subtitles of a movie to its audio, for subtitles made
+// for another cut or release. It finds the global offset and frame rate
+// change that best match cues to speech, follows drift with local offsets,
+// and moves the cues of all languages accordingly.
+func (s Subtitle) AutoSync(req AutoSyncRequest) (SyncReport, error) {
+	report := SyncReport{Scale: 1, Anchors: []SyncAnchor{}, Changes: []TimingChange{}}
+
+	maxOffset := time.Duration(req.MaxOffsetSeconds) * time.Second
+	if maxOffset == 0 {
+		maxOffset = defaultMaxSyncOffset
+	}
+	if maxOffset < 0 || maxOffset > maxSyncOffsetLimit {
+		return report, fmt.Errorf("maximum offset must be between 0 and %d seconds", int(maxSyncOffsetLimit.Seconds()))
+	}
+
+	subtitles, err := getAllSubtitles(req.MovieID)
+	if err != nil {
+		return report, err
+	}
+	cues := timedCues(subtitles)
+	report.Checked = len(cues)
+	if len(cues) == 0 {
+		return report, nil
+	}
+
+	ctx := context.Background()
+	media, _, err := primaryMedia(ctx, req.MovieID)
+	if err != nil {
+		return report, err
+	}
+	wavPath, cleanup, err := movieAudioWAV(ctx, req.MovieID)
+	if err != nil {
+		return report, err
+	}
+	defer cleanup()
+
+	speech, total, err := wavSpeech(wavPath)
+	if err != nil {
+		return report, fmt.Errorf("failed to detect speech: %w", err)
+	}
+	if len(speech) == 0 {
+		return report, errors.New("no speech was found in the audio")
+	}
+
+	fit := fitSync(cues, speech, total, maxOffset)
+	report.OffsetMs, report.Scale = fit.offset.Milliseconds(), fit.scale
+	if fit.anchors != nil {
+		report.Anchors = fit.anchors
+	}
+
+	// Cues are aligned to the frames of a linked video, and kept from
+	// running past the end of the media
+	for _, cue := range cues {
+		start, end := max(fit.apply(cue.start), 0), fit.apply(cue.end)
+		if end <= start {
+			end = start + cue.end - cue.start
+		}
+		if duration := media.Duration(); duration > 0 && start < duration {
+			end = min(end, duration)
+		}
+		start, end = alignToFrame(start, media.Framerate), alignToFrame(end, media.Framerate)
+		startTime, endTime := formatTimestamp(start), formatTimestamp(end)
+		if startTime == cue.subtitle.StartTime && endTime == cue.subtitle.EndTime {
+			continue
+		}
+		report.Changes = append(report.Changes, TimingChange{
+			ID:                cue.subtitle.ID,
+			SlNo:              cue.subtitle.SlNo,
+			PreviousStartTime: cue.subtitle.StartTime,
+			PreviousEndTime:   cue.subtitle.EndTime,
+			StartTime:         startTime,
+			EndTime:           endTime,
+		})
+	}
+
+	if req.DryRun {
+		return report, nil
+	}
+	return report, applyTimingChanges(req.MovieID, report.Changes)
+}
@@ -0,0 +1,305 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"infinity-subtitle/backend/database"
+)
+
+const (
+	defaultSnapTolerance = 300 * time.Millisecond
+	maxSnapTolerance     = 2 * time.Second
+	// minSnappedCueDuration keeps snapping from squeezing a cue unreadable
+	minSnappedCueDuration = 500 * time.Millisecond
+)
+
+// SnapToSpeechRequest asks to move cue boundaries onto detected speech.
+type SnapToSpeechRequest struct {
+	MovieID int `json:"movie_id"`
+	// ToleranceMs is how far a start or end may move, 300 ms if zero
+	ToleranceMs int `json:"tolerance_ms"`
+	// DryRun reports the changes without saving them
+	DryRun bool `json:"dry_run"`
+}
+
+// TimingChange is a cue whose timing was adjusted.
+type TimingChange struct {
+	ID                int    `json:"id"`
+	SlNo              int    `json:"sl_no"`
+	PreviousStartTime string `json:"previous_start_time"`
+	PreviousEndTime   string `json:"previous_end_time"`
+	StartTime         string `json:"start_time"`
+	EndTime           string `json:"end_time"`
+}
+
+// TimingReport lists the cues a timing operation moved out of those it checked.
+type TimingReport struct {
+	Checked int            `json:"checked"`
+	Changes []TimingChange `json:"changes"`
+}
+
+// Issues reported by ValidateTiming
+const (
+	TimingIssueInvalidTime     = "invalid_time"
+	TimingIssueEndsBeforeStart = "ends_before_start"
+	// TimingIssueBeyondMedia is a cue that ends after the movie's media
+	TimingIssueBeyondMedia = "beyond_media"
+)
+
+// TimingIssue is a cue whose timing is wrong.
+type TimingIssue struct {
+	ID        int    `json:"id"`
+	SlNo      int    `json:"sl_no"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Issue     string `json:"issue"`
+}
+
+// TimingValidation lists the cues of a movie with wrong timing.
+// MediaDurationMs is what cues were checked against, zero if the movie has no
+// linked media.
+type TimingValidation struct {
+	Checked         int           `json:"checked"`
+	MediaDurationMs int64         `json:"media_duration_ms"`
+	Issues          []TimingIssue `json:"issues"`
+}
+
+// timedCue is a subtitle with its parsed timing.
+type timedCue struct {
+	subtitle   Subtitle
+	start, end time.Duration
+}
+
+// timedCues parses the timing of subtitles and orders them by start.
+// Subtitles with an invalid time are left out.
+func timedCues(subtitles []Subtitle) []timedCue {
+	cues := make([]timedCue, 0, len(subtitles))
+	for _, subtitle := range subtitles {
+		start, startErr := parseTimestamp(subtitle.StartTime)
+		end, endErr := parseTimestamp(subtitle.EndTime)
+		if startErr != nil || endErr != nil {
+			continue
+		}
+		cues = append(cues, timedCue{subtitle: subtitle, start: start, end: end})
+	}
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].start < cues[j].start })
+	return cues
+}
+
+// alignToFrame moves a time to the start of the nearest video frame, so that
+// cues change with the picture. Times are kept if the framerate is unknown.
+func alignToFrame(d time.Duration, framerate float64) time.Duration {
+	if framerate <= 0 {
+		return d
+	}
+	return time.Duration(math.Round(d.Seconds()*framerate) / framerate * float64(time.Second))
+}
+
+// nearestBoundary returns the boundary closest to t, if one is within
+// tolerance. Boundaries are sorted.
+func nearestBoundary(boundaries []time.Duration, t time.Duration, tolerance time.Duration) (time.Duration, bool) {
+	i := sort.Search(len(boundaries), func(i int) bool { return boundaries[i] >= t })
+	best, found := t, false
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(boundaries) {
+			continue
+		}
+		distance := (boundaries[j] - t).Abs()
+		if distance <= tolerance && (!found || distance < (best-t).Abs()) {
+			best, found = boundaries[j], true
+		}
+	}
+	return best, found
+}
+
+// snapCues moves the start of each cue to the nearest start of speech and its
+// end to the nearest end of speech, within tolerance. A cue may grow into at
+// most half of the gap to its neighbours, so cues that did not overlap still
+// do not, and a cue that would get shorter than minSnappedCueDuration keeps
+// its timing. Snapped times are aligned to frames of the given framerate.
+func snapCues(cues []timedCue, speech []speechSegment, tolerance time.Duration, framerate float64) []TimingChange {
+	starts := make([]time.Duration, len(speech))
+	ends := make([]time.Duration, len(speech))
+	for i, segment := range speech {
+		starts[i], ends[i] = segment.Start, segment.End
+	}
+
+	changes := []TimingChange{}
+	for i, cue := range cues {
+		// Cues overlapping a neighbour are not moved further into it
+		lo, hi := cue.start, cue.end
+		if i == 0 {
+			lo = 0
+		} else if prev := cues[i-1]; prev.end <= cue.start {
+			lo = (prev.end + cue.start) / 2
+		}
+		if i == len(cues)-1 {
+			hi = cue.end + tolerance
+		} else if next := cues[i+1]; cue.end <= next.start {
+			hi = (cue.end + next.start) / 2
+		}
+
+		start, end := cue.start, cue.end
+		if s, ok := nearestBoundary(starts, cue.start, tolerance); ok && s >= lo {
+			start = alignToFrame(s, framerate)
+		}
+		if e, ok := nearestBoundary(ends, cue.end, tolerance); ok && e <= hi {
+			end = alignToFrame(e, framerate)
+		}
+		if end-start < minSnappedCueDuration {
+			continue
+		}
+		if start == cue.start && end == cue.end {
+			continue
+		}
+
+		changes = append(changes, TimingChange{
+			ID:                cue.subtitle.ID,
+			SlNo:              cue.subtitle.SlNo,
+			PreviousStartTime: cue.subtitle.StartTime,
+			PreviousEndTime:   cue.subtitle.EndTime,
+			StartTime:         formatTimestamp(start),
+			EndTime:           formatTimestamp(end),
+		})
+	}
+	return changes
+}
+
+// applyTimingChanges saves the new timing of cues.
+func applyTimingChanges(movieID int, changes []TimingChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		UPDATE subtitles SET start_time = ?, end_time = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE movie_id = ? AND id = ?
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, change := range changes {
+		if _, err := stmt.Exec(change.StartTime, change.EndTime, movieID, change.ID); err != nil {
+			return fmt.Errorf("failed to update subtitle timing: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// SnapToSpeech moves the start and end of each cue of a movie to the nearest
+// speech boundary in its audio, within the tolerance, and reports the cues
+// that moved. Transcribed and imported cues often start early or linger
+// after the speech ends. Movies with a linked video are snapped to its frames.
+func (s Subtitle) SnapToSpeech(req SnapToSpeechRequest) (TimingReport, error) {
+	report := TimingReport{Changes: []TimingChange{}}
+
+	tolerance := time.Duration(req.ToleranceMs) * time.Millisecond
+	if tolerance == 0 {
+		tolerance = defaultSnapTolerance
+	}
+	if tolerance < 0 || tolerance > maxSnapTolerance {
+		return report, fmt.Errorf("tolerance must be between 0 and %d ms", maxSnapTolerance.Milliseconds())
+	}
+
+	subtitles, err := getAllSubtitles(req.MovieID)
+	if err != nil {
+		return report, err
+	}
+	cues := timedCues(subtitles)
+	report.Checked = len(cues)
+	if len(cues) == 0 {
+		return report, nil
+	}
+
+	ctx := context.Background()
+	media, _, err := primaryMedia(ctx, req.MovieID)
+	if err != nil {
+		return report, err
+	}
+	wavPath, cleanup, err := movieAudioWAV(ctx, req.MovieID)
+	if err != nil {
+		return report, err
+	}
+	defer cleanup()
+
+	speech, _, err := wavSpeech(wavPath)
+	if err != nil {
+		return report, fmt.Errorf("failed to detect speech: %w", err)
+	}
+	if len(speech) == 0 {
+		return report, errors.New("no speech was found in the audio")
+	}
+
+	report.Changes = snapCues(cues, speech, tolerance, media.Framerate)
+	if req.DryRun {
+		return report, nil
+	}
+	return report, applyTimingChanges(req.MovieID, report.Changes)
+}
+
+// validateTiming checks the timing of each subtitle, and that it ends within
+// the media if its duration is known.
+func validateTiming(subtitles []Subtitle, duration time.Duration) []TimingIssue {
+	issues := []TimingIssue{}
+	for _, subtitle := range subtitles {
+		issue := ""
+		start, startErr := parseTimestamp(subtitle.StartTime)
+		end, endErr := parseTimestamp(subtitle.EndTime)
+		switch {
+		case startErr != nil || endErr != nil:
+			issue = TimingIssueInvalidTime
+		case end <= start:
+			issue = TimingIssueEndsBeforeStart
+		case duration > 0 && end > duration:
+			issue = TimingIssueBeyondMedia
+		default:
+			continue
+		}
+		issues = append(issues, TimingIssue{
+			ID:        subtitle.ID,
+			SlNo:      subtitle.SlNo,
+			StartTime: subtitle.StartTime,
+			EndTime:   subtitle.EndTime,
+			Issue:     issue,
+		})
+	}
+	return issues
+}
+
+// ValidateTiming reports the cues of a movie whose times are invalid, that
+// end before they start, or that run past the end of its primary media.
+func (s Subtitle) ValidateTiming(movieID int) (TimingValidation, error) {
+	validation := TimingValidation{Issues: []TimingIssue{}}
+
+	media, _, err := primaryMedia(context.Background(), movieID)
+	if err != nil {
+		return validation, err
+	}
+	subtitles, err := getAllSubtitles(movieID)
+	if err != nil {
+		return validation, err
+	}
+
+	validation.Checked = len(subtitles)
+	validation.MediaDurationMs = media.DurationMs
+	validation.Issues = validateTiming(subtitles, media.Duration())
+	return validation, nil
+}
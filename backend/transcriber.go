@@ -0,0 +1,344 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"infinity-subtitle/backend/logger"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	TranscriberOpenAI      = "openai"
+	TranscriberLocalServer = "local_server"
+	TranscriberExecutable  = "executable"
+
+	defaultServerModel = "whisper-1"
+	// defaultTranscriberArguments runs whisper.cpp
+	defaultTranscriberArguments = "-m models/ggml-base.bin -f {input} -l {language} -osrt -of {output}"
+)
+
+// Transcriber turns an audio file into timed text.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audioPath string, language string) (Transcription, error)
+}
+
+// Transcription is the timed text of a recording. Words are optional; without
+// them the segments are split into cues by their length.
+type Transcription struct {
+	Language string
+	Duration time.Duration
+	Text     string
+	Segments []transcriptSegment
+	Words    []transcriptWord
+}
+
+// TranscriberSettings selects the transcriber used when a queue job doesn't
+// name one, and configures the local ones.
+type TranscriberSettings struct {
+	Default string `json:"default"`
+	// ServerURL is the base URL of an OpenAI compatible server, such as http://localhost:8000/v1
+	ServerURL   string `json:"server_url"`
+	ServerModel string `json:"server_model"`
+	// Executable runs whisper.cpp, faster-whisper or similar with Arguments,
+	// in which {input}, {language}, {output} and {output_dir} are replaced.
+	// Speaker labels written by diarizing programs such as WhisperX are kept.
+	Executable string `json:"executable"`
+	Arguments  string `json:"arguments"`
+}
+
+func getTranscriberSettings() TranscriberSettings {
+	settings := TranscriberSettings{
+		Default:     os.Getenv("TRANSCRIBER"),
+		ServerURL:   os.Getenv("TRANSCRIBER_SERVER_URL"),
+		ServerModel: os.Getenv("TRANSCRIBER_SERVER_MODEL"),
+		Executable:  os.Getenv("TRANSCRIBER_EXECUTABLE"),
+		Arguments:   os.Getenv("TRANSCRIBER_ARGUMENTS"),
+	}
+	if settings.Default == "" {
+		settings.Default = TranscriberOpenAI
+	}
+	if settings.ServerModel == "" {
+		settings.ServerModel = defaultServerModel
+	}
+	if settings.Arguments == "" {
+		settings.Arguments = defaultTranscriberArguments
+	}
+	return settings
+}
+
+func (s *Setting) GetTranscriberSettings() (TranscriberSettings, error) {
+	return getTranscriberSettings(), nil
+}
+
+func (s *Setting) SaveTranscriberSettings(settings TranscriberSettings) error {
+	if err := validateTranscriber(settings.Default); err != nil {
+		return err
+	}
+	if settings.ServerURL != "" {
+		if u, err := url.Parse(settings.ServerURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid server URL %q", settings.ServerURL)
+		}
+	}
+	if settings.Arguments != "" && !strings.Contains(settings.Arguments, "{input}") {
+		return errors.New("arguments must contain {input}")
+	}
+
+	return saveEnvValues(map[string]string{
+		"TRANSCRIBER":              settings.Default,
+		"TRANSCRIBER_SERVER_URL":   strings.TrimSpace(settings.ServerURL),
+		"TRANSCRIBER_SERVER_MODEL": strings.TrimSpace(settings.ServerModel),
+		"TRANSCRIBER_EXECUTABLE":   strings.TrimSpace(settings.Executable),
+		"TRANSCRIBER_ARGUMENTS":    strings.TrimSpace(settings.Arguments),
+	})
+}
+
+// validateTranscriber accepts a transcriber name, or an empty one for the default.
+func validateTranscriber(name string) error {
+	switch name {
+	case "", TranscriberOpenAI, TranscriberLocalServer, TranscriberExecutable:
+		return nil
+	}
+	return fmt.Errorf("unknown transcriber %q", name)
+}
+
+// transcriberIsBilled reports whether the named transcriber, or the default
+// one if name is empty, is the paid OpenAI API.
+func transcriberIsBilled(name string) bool {
+	if name == "" {
+		name = getTranscriberSettings().Default
+	}
+	return name == TranscriberOpenAI
+}
+
+// newTranscriber returns the named transcriber, or the default one if name is
+// empty. Usage is recorded against key for OpenAI only, the others are free.
+func newTranscriber(name string, key usageKey) (Transcriber, error) {
+	settings := getTranscriberSettings()
+	if name == "" {
+		name = settings.Default
+	}
+
+	switch name {
+	case TranscriberOpenAI:
+		client, err := newOpenAIClient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OpenAI client: %w", err)
+		}
+		return &openAITranscriber{client: client, model: modelName, billed: true, usage: key}, nil
+	case TranscriberLocalServer:
+		if settings.ServerURL == "" {
+			return nil, errors.New("local transcription server URL is not set")
+		}
+		// The OpenAI key is not sent to other servers
+		config := openai.DefaultConfig("")
+		config.BaseURL = strings.TrimRight(settings.ServerURL, "/")
+		config.HTTPClient = &http.Client{}
+		return &openAITranscriber{client: openai.NewClientWithConfig(config), model: settings.ServerModel}, nil
+	case TranscriberExecutable:
+		if settings.Executable == "" {
+			return nil, errors.New("transcription executable is not set")
+		}
+		return &executableTranscriber{path: settings.Executable, arguments: settings.Arguments}, nil
+	}
+
+	return nil, fmt.Errorf("unknown transcriber %q", name)
+}
+
+// openAITranscriber calls the OpenAI transcription API, or a local server
+// offering the same API.
+type openAITranscriber struct {
+	client *openai.Client
+	model  string
+	// billed is set for OpenAI itself, whose quota and prices apply
+	billed bool
+	usage  usageKey
+}
+
+func (t *openAITranscriber) Transcribe(ctx context.Context, audioPath string, language string) (Transcription, error) {
+	// Cues are built from the word timings rather than taken from an SRT
+	// response, which ignores subtitle conventions
+	req := openai.AudioRequest{
+		Model:    t.model,
+		FilePath: audioPath,
+		Language: language,
+		Format:   openai.AudioResponseFormatVerboseJSON,
+		TimestampGranularities: []openai.TranscriptionTimestampGranularity{
+			openai.TranscriptionTimestampGranularityWord,
+			openai.TranscriptionTimestampGranularitySegment,
+		},
+	}
+
+	return t.request(ctx, req, t.client.CreateTranscription, UsageOperationTranscription)
+}
+
+// TranslateAudio transcribes a recording straight into English. The
+// translations endpoint gives no word timestamps, so cues follow its segments.
+func (t *openAITranscriber) TranslateAudio(ctx context.Context, audioPath string) (Transcription, error) {
+	req := openai.AudioRequest{
+		Model:    t.model,
+		FilePath: audioPath,
+		Format:   openai.AudioResponseFormatVerboseJSON,
+	}
+
+	transcription, err := t.request(ctx, req, t.client.CreateTranslation, UsageOperationAudioTranslation)
+	transcription.Language = "en"
+	return transcription, err
+}
+
+func (t *openAITranscriber) request(ctx context.Context, req openai.AudioRequest,
+	create func(context.Context, openai.AudioRequest) (openai.AudioResponse, error), operation string) (Transcription, error) {
+	if t.billed {
+		if err := getAPILimiter().Wait(ctx, 0); err != nil {
+			return Transcription{}, fmt.Errorf("rate limit exceeded: %w", err)
+		}
+	}
+
+	resp, err := create(ctx, req)
+	if err != nil {
+		return Transcription{}, err
+	}
+	transcription := transcriptionFromResponse(resp)
+
+	if t.billed {
+		// Whisper bills the length of the audio; the last segment is the fallback
+		duration := transcription.Duration
+		if duration == 0 && len(transcription.Segments) > 0 {
+			duration = transcription.Segments[len(transcription.Segments)-1].End
+		}
+		recordUsage(t.usage, t.model, operation, 0, 0, duration.Seconds())
+	}
+
+	return transcription, nil
+}
+
+func transcriptionFromResponse(resp openai.AudioResponse) Transcription {
+	transcription := Transcription{
+		Language: resp.Language,
+		Duration: seconds(resp.Duration),
+		Text:     resp.Text,
+	}
+	for _, segment := range resp.Segments {
+		transcription.Segments = append(transcription.Segments, transcriptSegment{
+			Text:  segment.Text,
+			Start: seconds(segment.Start),
+			End:   seconds(segment.End),
+		})
+	}
+	for _, word := range resp.Words {
+		transcription.Words = append(transcription.Words, transcriptWord{
+			Text:  strings.TrimSpace(word.Word),
+			Start: seconds(word.Start),
+			End:   seconds(word.End),
+		})
+	}
+	return transcription
+}
+
+// executableTranscriber runs a local speech recognition program that writes
+// an SRT file, so that the audio never leaves the machine.
+type executableTranscriber struct {
+	path      string
+	arguments string
+}
+
+func (t *executableTranscriber) Transcribe(ctx context.Context, audioPath string, language string) (Transcription, error) {
+	log, err := logger.GetLogger()
+	if err != nil {
+		return Transcription{}, fmt.Errorf("failed to get logger: %w", err)
+	}
+
+	outputDir, err := os.MkdirTemp("", "transcription-*")
+	if err != nil {
+		return Transcription{}, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	// whisper.cpp only reads 16 kHz WAV, which ffmpeg can make of anything
+	if format, err := parseWAVFile(audioPath); err != nil || format.SampleRate != chunkSampleRate {
+		if wavPath, err := convertToWAV(ctx, audioPath); err == nil {
+			defer os.Remove(wavPath)
+			audioPath = wavPath
+		}
+	}
+
+	if language == "" {
+		language = "auto"
+	}
+	replacer := strings.NewReplacer(
+		"{input}", audioPath,
+		"{language}", language,
+		"{output}", filepath.Join(outputDir, "transcript"),
+		"{output_dir}", outputDir,
+	)
+	// Placeholders are replaced after splitting so that paths may contain spaces
+	args := strings.Fields(t.arguments)
+	for i, arg := range args {
+		args[i] = replacer.Replace(arg)
+	}
+
+	log.Info("Running %s %s", t.path, strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, t.path, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return Transcription{}, fmt.Errorf("transcription program failed: %w: %s", err, lastLines(string(output), 5))
+	}
+
+	srtFiles, err := filepath.Glob(filepath.Join(outputDir, "*.srt"))
+	if err != nil || len(srtFiles) == 0 {
+		return Transcription{}, errors.New("transcription program wrote no SRT file; check that its arguments write one to {output} or {output_dir}")
+	}
+	content, err := os.ReadFile(srtFiles[0])
+	if err != nil {
+		return Transcription{}, fmt.Errorf("failed to read transcription: %w", err)
+	}
+
+	transcription := Transcription{Duration: srtDuration(string(content))}
+	var texts []string
+	for _, cue := range parseSRT(string(content)) {
+		start, startErr := parseTimestamp(cue.StartTime)
+		end, endErr := parseTimestamp(cue.EndTime)
+		if startErr != nil || endErr != nil {
+			continue
+		}
+		// Diarizing programs such as WhisperX label the speaker of each cue
+		speaker, text := splitSpeakerLabel(normalizeText(cue.Text))
+		if speaker == "" {
+			speaker = cue.Speaker
+		}
+		transcription.Segments = append(transcription.Segments, transcriptSegment{
+			Text:    text,
+			Start:   start,
+			End:     end,
+			Speaker: sanitizeSpeaker(speaker),
+		})
+		texts = append(texts, text)
+	}
+	transcription.Text = strings.Join(texts, " ")
+
+	return transcription, nil
+}
+
+// parseWAVFile reads the WAV header of a file.
+func parseWAVFile(path string) (WAVFormat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return WAVFormat{}, err
+	}
+	defer file.Close()
+	return parseWAVHeader(file)
+}
+
+// lastLines keeps the end of a program's output for error messages.
+func lastLines(output string, n int) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.Join(lines[max(len(lines)-n, 0):], "\n")
+}
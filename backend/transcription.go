@@ -5,47 +5,109 @@ import (
 	"fmt"
 	"infinity-subtitle/backend/logger"
 	"os"
-
-	openai "github.com/sashabaranov/go-openai"
 )
 
 const (
 	modelName = "whisper-1"
 )
 
-func transcribeAudio(audioPath string, language string) (string, error) {
-	// Create OpenAI client
-	openaiClient := openai.NewClient(os.Getenv("OPENAI_API_KEY"))
-
+// transcribeAudio transcribes an audio file to SRT and returns it with the
+// code of the spoken language. An empty or "auto" language is detected.
+// Files over the upload limit and WAV recordings longer than
+// maxChunkDuration are split at pauses and transcribed chunk by chunk,
+// except by local programs, which take the whole recording.
+func transcribeAudio(ctx context.Context, transcriber Transcriber, audioPath string,
+	language string) (string, string, error) {
 	logger, err := logger.GetLogger()
 	if err != nil {
-		return "", fmt.Errorf("failed to create logger: %w", err)
+		return "", "", fmt.Errorf("failed to create logger: %w", err)
 	}
 
 	// Open the audio file
 	audioFile, err := os.Open(audioPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open audio file: %w", err)
+		return "", "", fmt.Errorf("failed to open audio file: %w", err)
 	}
 	defer audioFile.Close()
 
-	// Create transcription request
-	req := openai.AudioRequest{
-		Model:    modelName,
-		FilePath: audioPath,
-		Language: language,
-		Prompt:   "Please transcribe the audio file to srt format",
-		Format:   openai.AudioResponseFormatSRT,
+	info, err := audioFile.Stat()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read audio file: %w", err)
+	}
+
+	if language == AutoLanguage {
+		language = ""
+	}
+	transcribe := func(ctx context.Context, path string) (string, string, error) {
+		return transcribeFile(ctx, transcriber, path, language)
+	}
+
+	// Local programs have no upload limit and number the speakers of the whole
+	// recording, which they could not do chunk by chunk
+	if _, ok := transcriber.(*executableTranscriber); ok {
+		logger.Info("Transcribing audio file: %s", audioPath)
+		srtContent, detected, err := transcribe(ctx, audioPath)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create transcription: %w", err)
+		}
+		return srtContent, detected, nil
+	}
+
+	format, err := parseWAVHeader(audioFile)
+	if err != nil && info.Size() > whisperUploadLimit {
+		// Only WAV can be split, so larger files of other formats are decoded first
+		logger.Info("Converting %s to WAV to split it", audioPath)
+		wavPath, err := convertToWAV(ctx, audioPath)
+		if err != nil {
+			return "", "", err
+		}
+		defer os.Remove(wavPath)
+		return transcribeAudio(ctx, transcriber, wavPath, language)
+	}
+
+	if err == nil && needsChunking(format, info.Size()) {
+		logger.Info("Transcribing audio file in chunks: %s", audioPath)
+		srtContent, detected, err := transcribeInChunks(ctx, transcribe, audioFile, format)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create transcription: %w", err)
+		}
+		logger.Info("Transcription completed: %+v", srtContent)
+		return srtContent, detected, nil
 	}
 
-	// Send request to OpenAI
 	logger.Info("Transcribing audio file: %s", audioPath)
-	resp, err := openaiClient.CreateTranscription(context.Background(), req)
+	srtContent, detected, err := transcribe(ctx, audioPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create transcription: %w", err)
+	}
+
+	logger.Info("Transcription completed: %+v", srtContent)
+
+	return srtContent, detected, nil
+}
+
+// transcribeFile transcribes a single audio file and segments it into cues.
+// Without a language hint the language reported by the transcriber is used.
+// Transcribers echo a hint back as the reported language, so with one the
+// language is detected from the text, and left empty if that is unclear.
+func transcribeFile(ctx context.Context, transcriber Transcriber, audioPath string,
+	language string) (string, string, error) {
+	transcription, err := transcriber.Transcribe(ctx, audioPath, language)
 	if err != nil {
-		return "", fmt.Errorf("failed to create transcription: %w", err)
+		return "", "", err
 	}
 
-	logger.Info("Transcription completed: %+v", resp.Text)
+	detected := ""
+	if language == "" {
+		detected = transcriptionLanguageCode(transcription.Language)
+	}
+	if detected == "" {
+		var confidence float64
+		detected, confidence = detectLanguage(transcription.Text)
+		if language != "" && confidence < minMismatchConfidence {
+			detected = ""
+		}
+	}
 
-	return resp.Text, nil
+	return formatSRT(segmentTranscription(transcription)), detected, nil
 }
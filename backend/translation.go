@@ -3,33 +3,53 @@ package backend
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
 	"sync"
-	"time"
 
 	"infinity-subtitle/backend/logger"
 
 	"runtime"
 
-	_ "github.com/joho/godotenv/autoload"
 	"github.com/sashabaranov/go-openai"
-	"golang.org/x/time/rate"
+	"github.com/sashabaranov/go-openai/jsonschema"
 )
 
 type TextToTranslate struct {
 	ID          int    `json:"id"`
 	SourceText  string `json:"source_text"`
 	Translation string `json:"translation"`
+	// MaxChars limits the length of the translation, 0 means no limit
+	MaxChars int `json:"max_chars,omitempty"`
 }
 
 type TranslationService struct {
-	client      *openai.Client
-	rateLimiter *rate.Limiter
-	logger      *logger.Logger
+	client  *openai.Client
+	limiter *apiLimiter
+	logger  *logger.Logger
+	// usage attributes the tokens spent by this service
+	usage usageKey
+	// constraints, if set, limits the length and line layout of translations
+	constraints *SubtitleConstraints
+	// instruction is an optional request from the editor, such as a tone
+	instruction string
+	// progress, if set, is called after every finished batch
+	progress func(done int, total int)
+	// refreshCache skips cached translations, new ones are still stored
+	refreshCache bool
+	// cacheHits counts the texts taken from the translation cache
+	cacheHits int
 }
 
-const retryCount = 3
+const (
+	retryCount = 3
+
+	translationModel     = openai.GPT4oMini
+	translationBatchSize = 20
+	// translationPromptTokens approximates the instructions sent with every batch
+	translationPromptTokens = 200
+)
 
 func NewTranslationService() (*TranslationService, error) {
 	log, err := logger.GetLogger()
@@ -37,13 +57,15 @@ func NewTranslationService() (*TranslationService, error) {
 		return nil, fmt.Errorf("failed to get logger: %w", err)
 	}
 
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	log.Info("Initializing translation service with API key: %s", apiKey)
+	client, err := newOpenAIClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OpenAI client: %w", err)
+	}
 
 	return &TranslationService{
-		client:      openai.NewClient(apiKey),
-		rateLimiter: rate.NewLimiter(rate.Every(time.Second/60), 1),
-		logger:      log,
+		client:  client,
+		limiter: getAPILimiter(),
+		logger:  log,
 	}, nil
 }
 
@@ -59,7 +81,33 @@ type TranslationRequest struct {
 }
 
 type TranslationResponse struct {
-	Translations map[string]string `json:"translations"`
+	Translations []TextToTranslate `json:"translations"`
+}
+
+// BatchError records a batch that could not be translated and the subtitle
+// ids it contained.
+type BatchError struct {
+	IDs   []int  `json:"ids"`
+	Error string `json:"error"`
+}
+
+// TranslationReport summarises a translation run for one target language.
+type TranslationReport struct {
+	Language        string       `json:"language"`
+	Translated      int          `json:"translated"`
+	UntranslatedIDs []int        `json:"untranslated_ids"`
+	Errors          []BatchError `json:"errors"`
+	// OverLimitIDs are translated subtitles still exceeding the length limits
+	OverLimitIDs []int `json:"over_limit_ids"`
+	// Cancelled is set when the job was stopped before every batch ran
+	Cancelled bool `json:"cancelled"`
+	// CacheHits is how many subtitles were taken from the translation cache
+	CacheHits int `json:"cache_hits"`
+}
+
+// Complete reports whether every subtitle was translated.
+func (r TranslationReport) Complete() bool {
+	return len(r.UntranslatedIDs) == 0 && len(r.Errors) == 0
 }
 
 func (ts *TranslationService) translate(ctx context.Context, textsToTranslate []TextToTranslate,
@@ -68,7 +116,7 @@ func (ts *TranslationService) translate(ctx context.Context, textsToTranslate []
 		return make([]TextToTranslate, 0), nil
 	}
 
-	if err := ts.rateLimiter.Wait(ctx); err != nil {
+	if err := ts.limiter.Wait(ctx, estimateBatchTokens(textsToTranslate)); err != nil {
 		return nil, fmt.Errorf("rate limit exceeded: %w", err)
 	}
 
@@ -96,7 +144,7 @@ func (ts *TranslationService) translate(ctx context.Context, textsToTranslate []
 		ts.logger.Info("Retry attempt %d for %d blank translations", attempt, len(blankTranslations))
 
 		// Wait for rate limiter before retry
-		if err := ts.rateLimiter.Wait(ctx); err != nil {
+		if err := ts.limiter.Wait(ctx, estimateBatchTokens(blankTranslations)); err != nil {
 			return nil, fmt.Errorf("rate limit exceeded on retry %d: %w", attempt, err)
 		}
 
@@ -123,36 +171,108 @@ func (ts *TranslationService) translate(ctx context.Context, textsToTranslate []
 		}
 	}
 
+	if ts.constraints != nil {
+		translations = ts.enforceConstraints(ctx, translations, targetLang)
+	}
+
 	return translations, nil
 }
 
+// estimateBatchTokens approximates prompt and completion tokens for a batch.
+// The reply repeats the source text next to its translation, so it is counted
+// roughly twice on top of the prompt.
+func estimateBatchTokens(textsToTranslate []TextToTranslate) int {
+	tokens := translationPromptTokens
+	for _, text := range textsToTranslate {
+		tokens += 3 * estimateTokens(text.SourceText)
+	}
+	return tokens
+}
+
 // Extract the actual translation logic into a separate method
 func (ts *TranslationService) performTranslation(ctx context.Context, textsToTranslate []TextToTranslate,
 	sourceLang, targetLang string) ([]TextToTranslate, error) {
 
-	prompt := fmt.Sprintf("Translate the following data from %s to %s: use `SourceText` value as input "+
-		" and put output value to `Translation`.\n", sourceLang, targetLang)
+	pending := make([]TextToTranslate, 0, len(textsToTranslate))
 	for _, text := range textsToTranslate {
 		if text.Translation != "" {
 			continue
 		}
-		prompt += fmt.Sprintf("%+v\n", text)
+		pending = append(pending, TextToTranslate{ID: text.ID, SourceText: text.SourceText, MaxChars: text.MaxChars})
+	}
+	if len(pending) == 0 {
+		return textsToTranslate, nil
 	}
 
-	prompt += "\nOutput format should be ARRAY of JSON. Output format should be \n" +
-		"[{\"id\": 1, \"source_text\": \"\", \"translation\": \"\"}, {\"id\": 2, \"source_text\": \"\", \"translation\": \"\"}] " +
-		"\nDO NOT INCLUDE ```json"
+	prompt, err := ts.translationPrompt(pending, sourceLang, targetLang)
+	if err != nil {
+		return nil, err
+	}
 
+	return ts.requestTranslations(ctx, prompt, textsToTranslate, UsageOperationTranslation)
+}
+
+// translationPrompt asks for the translation of the given items.
+func (ts *TranslationService) translationPrompt(pending []TextToTranslate, sourceLang, targetLang string) (string, error) {
+	payload, err := json.Marshal(pending)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal texts to translate: %w", err)
+	}
+
+	prompt := fmt.Sprintf("Translate the `source_text` of every item below from %s to %s and put the result "+
+		"in `translation`. Keep `id` and `source_text` exactly as given, keep the line breaks of each item "+
+		"and return every item.", sourceLang, targetLang)
+	if ts.constraints != nil {
+		prompt += " " + ts.constraints.prompt()
+	}
+	if ts.instruction != "" {
+		prompt += " Follow this instruction from the editor: " + ts.instruction
+	}
+	prompt += "\n\n" + string(payload)
+
+	return prompt, nil
+}
+
+// requestTranslations sends a prompt expecting a TranslationResponse and
+// validates the reply against the batch it was built from.
+func (ts *TranslationService) requestTranslations(ctx context.Context, prompt string,
+	textsToTranslate []TextToTranslate, operation string) ([]TextToTranslate, error) {
+	choices, err := ts.requestTranslationChoices(ctx, prompt, textsToTranslate, operation, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	return choices[0], nil
+}
+
+// requestTranslationChoices is requestTranslations for n sampled replies. A
+// temperature of 0 keeps the model default. Replies that cannot be parsed are
+// skipped, as long as one of them can.
+func (ts *TranslationService) requestTranslationChoices(ctx context.Context, prompt string,
+	textsToTranslate []TextToTranslate, operation string, n int, temperature float32) ([][]TextToTranslate, error) {
 	resp, err := ts.client.CreateChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
-			Model: openai.GPT4oMini,
+			Model:       translationModel,
+			N:           n,
+			Temperature: temperature,
 			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: "You are a professional subtitle translator. Reply with JSON only.",
+				},
 				{
 					Role:    openai.ChatMessageRoleUser,
 					Content: prompt,
 				},
 			},
+			ResponseFormat: &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+				JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+					Name:   "subtitle_translations",
+					Schema: &translationResponseSchema,
+					Strict: true,
+				},
+			},
 		},
 	)
 	if err != nil {
@@ -165,32 +285,61 @@ func (ts *TranslationService) performTranslation(ctx context.Context, textsToTra
 		return nil, fmt.Errorf("no response from OpenAI")
 	}
 
-	ts.logger.Info("Translation response: %s", resp.Choices[0].Message.Content)
+	recordUsage(ts.usage, translationModel, operation,
+		resp.Usage.PromptTokens, resp.Usage.CompletionTokens, 0)
 
-	var translations []TextToTranslate
-	err = json.Unmarshal([]byte(resp.Choices[0].Message.Content), &translations)
-	if err != nil {
-		ts.logger.Error("Failed to parse translation response: %s", resp.Choices[0].Message.Content)
+	results := make([][]TextToTranslate, 0, len(resp.Choices))
+	for _, choice := range resp.Choices {
+		ts.logger.Info("Translation response: %s", choice.Message.Content)
+
+		translations, parseErr := parseTranslationResponse(choice.Message.Content)
+		if parseErr != nil {
+			ts.logger.Error("Failed to parse translation response: %s", choice.Message.Content)
+			err = parseErr
+			continue
+		}
+
+		validated, rejected := validateTranslations(textsToTranslate, translations)
+		for _, reason := range rejected {
+			ts.logger.Warn("Rejected translation: %s", reason)
+		}
+		results = append(results, validated)
+	}
+
+	if len(results) == 0 {
 		return nil, fmt.Errorf("failed to parse translation response: %w", err)
 	}
 
-	return translations, nil
+	return results, nil
 }
 
 func (ts *TranslationService) processBatch(ctx context.Context, textsToTranslate []TextToTranslate,
-	sourceLang, targetLang string) []TextToTranslate {
+	sourceLang, targetLang string) ([]TextToTranslate, []BatchError) {
 	ts.logger.Info("Processing subtitle file with %d lines", len(textsToTranslate))
 
 	var wg sync.WaitGroup
 	results := make([]TextToTranslate, 0)
+	batchErrors := make([]BatchError, 0)
 	mu := sync.Mutex{}
 
-	// Split texts into batches of 20
-	batchSize := 20
+	// Take the texts translated before from the cache
+	cached := ts.cachedTranslations(textsToTranslate, sourceLang, targetLang)
+	ts.cacheHits += len(cached)
+	if len(cached) > 0 {
+		ts.logger.Info("Found %d of %d lines in the translation cache", len(cached), len(textsToTranslate))
+	}
+
+	// Split texts into batches
+	batchSize := translationBatchSize
 	batches := make([][]TextToTranslate, 0)
 	currentBatch := make([]TextToTranslate, 0)
 
 	for _, textToTranslate := range textsToTranslate {
+		if translation, ok := cached[textToTranslate.ID]; ok {
+			textToTranslate.Translation = translation
+			results = append(results, textToTranslate)
+			continue
+		}
 		currentBatch = append(currentBatch, textToTranslate)
 		if len(currentBatch) >= batchSize {
 			batches = append(batches, currentBatch)
@@ -201,10 +350,25 @@ func (ts *TranslationService) processBatch(ctx context.Context, textsToTranslate
 		batches = append(batches, currentBatch)
 	}
 
+	done := 0
+	reportBatch := func(size int) {
+		if ts.progress == nil || ctx.Err() != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		done += size
+		ts.progress(done, len(textsToTranslate))
+	}
+	if len(results) > 0 {
+		reportBatch(len(results))
+	}
+
 	// Fan-out: Create multiple workers
 	workerCount := runtime.NumCPU()
 	batchChan := make(chan []TextToTranslate, len(batches))
 	resultChan := make(chan []TextToTranslate, len(batches))
+	errorChan := make(chan BatchError, len(batches))
 
 	// Start workers
 	for range workerCount {
@@ -213,9 +377,18 @@ func (ts *TranslationService) processBatch(ctx context.Context, textsToTranslate
 			defer wg.Done()
 			for batch := range batchChan {
 				translations, err := ts.translate(ctx, batch, sourceLang, targetLang)
-				if err == nil {
-					resultChan <- translations
+				reportBatch(len(batch))
+				if err != nil {
+					ts.logger.Error("Batch of %d lines failed: %v", len(batch), err)
+					ids := make([]int, 0, len(batch))
+					for _, text := range batch {
+						ids = append(ids, text.ID)
+					}
+					errorChan <- BatchError{IDs: ids, Error: err.Error()}
+					continue
 				}
+				ts.storeTranslations(translations, sourceLang, targetLang)
+				resultChan <- translations
 			}
 		}()
 	}
@@ -230,14 +403,120 @@ func (ts *TranslationService) processBatch(ctx context.Context, textsToTranslate
 	go func() {
 		wg.Wait()
 		close(resultChan)
+		close(errorChan)
 	}()
 
 	// Process results
 	for translations := range resultChan {
-		mu.Lock()
 		results = append(results, translations...)
-		mu.Unlock()
 	}
 
-	return results
+	for batchError := range errorChan {
+		batchErrors = append(batchErrors, batchError)
+	}
+
+	return results, batchErrors
+}
+
+// translationResponseSchema constrains chat completions to a TranslationResponse.
+var translationResponseSchema = jsonschema.Definition{
+	Type: jsonschema.Object,
+	Properties: map[string]jsonschema.Definition{
+		"translations": {
+			Type: jsonschema.Array,
+			Items: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"id":          {Type: jsonschema.Integer},
+					"source_text": {Type: jsonschema.String},
+					"translation": {Type: jsonschema.String},
+				},
+				Required:             []string{"id", "source_text", "translation"},
+				AdditionalProperties: false,
+			},
+		},
+	},
+	Required:             []string{"translations"},
+	AdditionalProperties: false,
+}
+
+// extractJSON returns the first JSON object or array found in content,
+// ignoring markdown fences and any prose around it.
+func extractJSON(content string) (json.RawMessage, error) {
+	start := strings.IndexAny(content, "{[")
+	if start == -1 {
+		return nil, errors.New("no JSON found in response")
+	}
+
+	var raw json.RawMessage
+	decoder := json.NewDecoder(strings.NewReader(content[start:]))
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+// parseTranslationResponse accepts either a TranslationResponse object or a
+// bare array of translations.
+func parseTranslationResponse(content string) ([]TextToTranslate, error) {
+	raw, err := extractJSON(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var translations []TextToTranslate
+	if raw[0] == '[' {
+		err = json.Unmarshal(raw, &translations)
+		return translations, err
+	}
+
+	var response TranslationResponse
+	if err = json.Unmarshal(raw, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Translations, nil
+}
+
+// validateTranslations matches translations to the batch by id. The result
+// keeps the batch order; items that were missing or rejected come back with a
+// blank translation so that they can be retried.
+func validateTranslations(batch []TextToTranslate, translations []TextToTranslate) ([]TextToTranslate, []string) {
+	results := make([]TextToTranslate, len(batch))
+	indexByID := make(map[int]int, len(batch))
+	for i, text := range batch {
+		results[i] = text
+		indexByID[text.ID] = i
+	}
+
+	var rejected []string
+	seen := make(map[int]bool, len(translations))
+
+	for _, translation := range translations {
+		i, ok := indexByID[translation.ID]
+		if !ok {
+			rejected = append(rejected, fmt.Sprintf("id %d was not in the batch", translation.ID))
+			continue
+		}
+		if seen[translation.ID] {
+			rejected = append(rejected, fmt.Sprintf("id %d was returned more than once", translation.ID))
+			continue
+		}
+		seen[translation.ID] = true
+
+		if normalizeText(translation.SourceText) != normalizeText(batch[i].SourceText) {
+			rejected = append(rejected, fmt.Sprintf("source_text of id %d was altered", translation.ID))
+			continue
+		}
+
+		results[i].Translation = strings.TrimSpace(translation.Translation)
+	}
+
+	return results, rejected
+}
+
+// normalizeText collapses whitespace so that reflowed source text still matches.
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
 }
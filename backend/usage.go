@@ -0,0 +1,366 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"infinity-subtitle/backend/database"
+	"infinity-subtitle/backend/logger"
+	"strings"
+	"time"
+)
+
+const (
+	UsageOperationTranslation   = "translation"
+	UsageOperationTranscription = "transcription"
+)
+
+// Usage is one OpenAI call as billed, with its estimated cost in USD.
+type Usage struct {
+	ID               int       `json:"id"`
+	MovieID          int       `json:"movie_id"`
+	QueueID          int       `json:"queue_id"`
+	Language         string    `json:"language"`
+	Model            string    `json:"model"`
+	Operation        string    `json:"operation"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	AudioSeconds     float64   `json:"audio_seconds"`
+	Cost             float64   `json:"cost"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// UsageTotal sums usage for one language and model.
+type UsageTotal struct {
+	Language         string  `json:"language"`
+	Model            string  `json:"model"`
+	Operation        string  `json:"operation"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	AudioSeconds     float64 `json:"audio_seconds"`
+	Cost             float64 `json:"cost"`
+}
+
+type UsageSummary struct {
+	Totals []UsageTotal `json:"totals"`
+	Cost   float64      `json:"cost"`
+}
+
+// ModelPrice holds USD prices used to estimate costs.
+type ModelPrice struct {
+	Model            string  `json:"model"`
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+	PerAudioMinute   float64 `json:"per_audio_minute"`
+}
+
+// Cost returns the estimated cost of the given usage at this price.
+func (p ModelPrice) Cost(promptTokens int, completionTokens int, audioSeconds float64) float64 {
+	return float64(promptTokens)*p.InputPerMillion/1e6 +
+		float64(completionTokens)*p.OutputPerMillion/1e6 +
+		audioSeconds/60*p.PerAudioMinute
+}
+
+// CostEstimate is the expected usage of a job that hasn't run yet.
+type CostEstimate struct {
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	AudioSeconds     float64  `json:"audio_seconds"`
+	Cost             float64  `json:"cost"`
+	Warnings         []string `json:"warnings"`
+}
+
+// usageKey identifies what an OpenAI call was made for.
+type usageKey struct {
+	MovieID  int
+	QueueID  int
+	Language string
+}
+
+func NewUsage() *Usage {
+	return &Usage{}
+}
+
+// getModelPrice finds the price of a model, matching dated model versions
+// such as gpt-4o-mini-2024-07-18 by their longest priced prefix.
+func getModelPrice(model string) (ModelPrice, error) {
+	db := database.GetDB()
+	var price ModelPrice
+	err := db.QueryRow(`
+		SELECT model, input_per_million, output_per_million, per_audio_minute
+		FROM model_prices
+		WHERE ? = model OR ? LIKE model || '-%'
+		ORDER BY LENGTH(model) DESC
+		LIMIT 1
+	`, model, model).Scan(&price.Model, &price.InputPerMillion, &price.OutputPerMillion, &price.PerAudioMinute)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ModelPrice{Model: model}, nil
+	}
+	if err != nil {
+		return price, fmt.Errorf("failed to get model price: %w", err)
+	}
+	return price, nil
+}
+
+// recordUsage stores a billed call. Failures are logged only, so that
+// accounting never breaks a translation.
+func recordUsage(key usageKey, model string, operation string, promptTokens int, completionTokens int, audioSeconds float64) {
+	logger, err := logger.GetLogger()
+	if err != nil {
+		return
+	}
+
+	price, err := getModelPrice(model)
+	if err != nil {
+		logger.Error("failed to record usage: %v", err)
+		return
+	}
+
+	db := database.GetDB()
+	_, err = db.Exec(`
+		INSERT INTO api_usage (movie_id, queue_id, language, model, operation, prompt_tokens, completion_tokens, audio_seconds, cost)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, nullableID(key.MovieID), nullableID(key.QueueID), key.Language, model, operation,
+		promptTokens, completionTokens, audioSeconds, price.Cost(promptTokens, completionTokens, audioSeconds))
+	if err != nil {
+		logger.Error("failed to record usage: %v", err)
+	}
+}
+
+// assignQueueUsageToMovie links usage recorded before a queue job had a movie.
+func assignQueueUsageToMovie(queueID int, movieID int) error {
+	db := database.GetDB()
+	_, err := db.Exec("UPDATE api_usage SET movie_id = ? WHERE queue_id = ? AND movie_id IS NULL", movieID, queueID)
+	if err != nil {
+		return fmt.Errorf("failed to assign usage to movie: %w", err)
+	}
+	return nil
+}
+
+func nullableID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id > 0}
+}
+
+func (u *Usage) GetModelPrices() ([]ModelPrice, error) {
+	db := database.GetDB()
+	rows, err := db.Query("SELECT model, input_per_million, output_per_million, per_audio_minute FROM model_prices ORDER BY model")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model prices: %w", err)
+	}
+	defer rows.Close()
+
+	prices := []ModelPrice{}
+	for rows.Next() {
+		var price ModelPrice
+		err := rows.Scan(&price.Model, &price.InputPerMillion, &price.OutputPerMillion, &price.PerAudioMinute)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan model price: %w", err)
+		}
+		prices = append(prices, price)
+	}
+
+	return prices, rows.Err()
+}
+
+func (u *Usage) SaveModelPrice(price ModelPrice) error {
+	if strings.TrimSpace(price.Model) == "" {
+		return errors.New("model is required")
+	}
+	if price.InputPerMillion < 0 || price.OutputPerMillion < 0 || price.PerAudioMinute < 0 {
+		return errors.New("prices cannot be negative")
+	}
+
+	db := database.GetDB()
+	_, err := db.Exec(`
+		INSERT INTO model_prices (model, input_per_million, output_per_million, per_audio_minute, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(model) DO UPDATE SET
+			input_per_million = excluded.input_per_million,
+			output_per_million = excluded.output_per_million,
+			per_audio_minute = excluded.per_audio_minute,
+			updated_at = CURRENT_TIMESTAMP
+	`, strings.TrimSpace(price.Model), price.InputPerMillion, price.OutputPerMillion, price.PerAudioMinute)
+	if err != nil {
+		return fmt.Errorf("failed to save model price: %w", err)
+	}
+
+	return nil
+}
+
+func (u *Usage) GetMovieUsage(movieID int) (UsageSummary, error) {
+	return getUsageSummary("movie_id", movieID)
+}
+
+func (u *Usage) GetQueueUsage(queueID int) (UsageSummary, error) {
+	return getUsageSummary("queue_id", queueID)
+}
+
+func getUsageSummary(column string, id int) (UsageSummary, error) {
+	summary := UsageSummary{Totals: []UsageTotal{}}
+
+	db := database.GetDB()
+	rows, err := db.Query(`
+		SELECT language, model, operation, COUNT(*), SUM(prompt_tokens), SUM(completion_tokens),
+			SUM(audio_seconds), SUM(cost)
+		FROM api_usage
+		WHERE `+column+` = ?
+		GROUP BY language, model, operation
+		ORDER BY language, model, operation
+	`, id)
+	if err != nil {
+		return summary, fmt.Errorf("failed to get usage: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var total UsageTotal
+		err := rows.Scan(&total.Language, &total.Model, &total.Operation, &total.Requests,
+			&total.PromptTokens, &total.CompletionTokens, &total.AudioSeconds, &total.Cost)
+		if err != nil {
+			return summary, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		summary.Totals = append(summary.Totals, total)
+		summary.Cost += total.Cost
+	}
+
+	return summary, rows.Err()
+}
+
+// EstimateTranslationCost estimates translating the untranslated subtitles of
+// a movie into the given languages.
+func (u *Usage) EstimateTranslationCost(movieID int, sourceLanguage string, targetLanguages []string) (CostEstimate, error) {
+	estimate := CostEstimate{Warnings: []string{}}
+
+	subtitles, err := getAllSubtitles(movieID)
+	if err != nil {
+		return estimate, err
+	}
+
+	for _, targetLanguage := range targetLanguages {
+		var texts []string
+		for _, subtitle := range subtitles {
+			if subtitle.Content[sourceLanguage] != "" && subtitle.Content[targetLanguage] == "" {
+				texts = append(texts, subtitle.Content[sourceLanguage])
+			}
+		}
+		estimate.addTranslation(texts)
+	}
+
+	return estimate, estimate.price()
+}
+
+// EstimateQueueCost estimates transcribing and translating files before they
+// are added to the queue.
+func (u *Usage) EstimateQueueCost(req []AddToQueueRequest) (CostEstimate, error) {
+	estimate := CostEstimate{Warnings: []string{}}
+
+	for _, r := range req {
+		targets := 0
+		for _, lang := range r.TargetLanguages {
+			// The English track of translated audio comes from the audio itself
+			if lang != r.SourceLanguage && !(r.TranslateAudio && lang == audioTranslationLanguage) {
+				targets++
+			}
+		}
+
+		var texts []string
+		if r.Type == "audio" {
+			duration, err := estimateAudioDuration(r)
+			if err != nil {
+				estimate.Warnings = append(estimate.Warnings, fmt.Sprintf("%s: %v", r.Name, err))
+				continue
+			}
+			// Local transcribers cost nothing
+			if transcriberIsBilled(r.Transcriber) {
+				estimate.AudioSeconds += duration.Seconds()
+				if r.TranslateAudio {
+					estimate.AudioSeconds += duration.Seconds()
+				}
+			}
+			// Assume a cue of about 40 characters every three seconds of audio
+			for range int(duration.Seconds() / 3) {
+				texts = append(texts, strings.Repeat(" ", 40))
+			}
+		} else {
+			if isVideoFileType(r.FileType) {
+				content, err := extractQueuedSubtitles(r)
+				if err != nil {
+					estimate.Warnings = append(estimate.Warnings, fmt.Sprintf("%s: %v", r.Name, err))
+					continue
+				}
+				r.Content = content
+			}
+			for _, cue := range parseSRT(r.Content) {
+				texts = append(texts, cue.Text)
+			}
+		}
+
+		for range targets {
+			estimate.addTranslation(texts)
+		}
+	}
+
+	return estimate, estimate.price()
+}
+
+// addTranslation adds the tokens of translating texts in the usual batches.
+func (e *CostEstimate) addTranslation(texts []string) {
+	for start := 0; start < len(texts); start += translationBatchSize {
+		batch := make([]TextToTranslate, 0, translationBatchSize)
+		for _, text := range texts[start:min(start+translationBatchSize, len(texts))] {
+			batch = append(batch, TextToTranslate{SourceText: text})
+		}
+
+		prompt := translationPromptTokens
+		for _, text := range batch {
+			prompt += estimateTokens(text.SourceText)
+		}
+		e.PromptTokens += prompt
+		e.CompletionTokens += estimateBatchTokens(batch) - prompt
+	}
+}
+
+func (e *CostEstimate) price() error {
+	translationPrice, err := getModelPrice(translationModel)
+	if err != nil {
+		return err
+	}
+	transcriptionPrice, err := getModelPrice(modelName)
+	if err != nil {
+		return err
+	}
+
+	e.Cost = translationPrice.Cost(e.PromptTokens, e.CompletionTokens, 0) +
+		transcriptionPrice.Cost(0, 0, e.AudioSeconds)
+	return nil
+}
+
+// estimateAudioDuration reads the duration of an uploaded WAV or video file.
+func estimateAudioDuration(r AddToQueueRequest) (time.Duration, error) {
+	video := isVideoFileType(r.FileType)
+	if !strings.EqualFold(r.FileType, "wav") && !video {
+		return 0, fmt.Errorf("duration of %s files cannot be estimated", r.FileType)
+	}
+
+	path, _, err := findMedia(r.MediaHash, r.FileType)
+	if err != nil {
+		return 0, err
+	}
+
+	if video {
+		probe, err := probeMedia(context.Background(), path)
+		if err != nil {
+			return 0, err
+		}
+		return probe.Duration, nil
+	}
+
+	format, err := parseWAVFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return format.Duration(), nil
+}
@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+const (
+	// vadFloorPercentile is the share of frames assumed to be background noise
+	vadFloorPercentile = 0.1
+	// vadThresholdRatio puts the speech threshold about 12 dB above the noise floor
+	vadThresholdRatio = 4
+	// vadMinThreshold keeps digital silence from making every breath speech
+	vadMinThreshold = 0.005
+	// vadMinSpeech drops clicks and other bursts too short to be a word
+	vadMinSpeech = 120 * time.Millisecond
+	// vadMinPause is the shortest silence that ends speech; shorter gaps are
+	// pauses between words
+	vadMinPause = 250 * time.Millisecond
+)
+
+// speechSegment is a stretch of a recording where someone speaks.
+type speechSegment struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// detectSpeech finds speech in an energy envelope of energyFrame frames.
+// Frames well above the noise floor of the recording are speech; pauses
+// shorter than vadMinPause are bridged and bursts shorter than vadMinSpeech
+// dropped.
+func detectSpeech(energy []float64) []speechSegment {
+	if len(energy) == 0 {
+		return nil
+	}
+
+	sorted := slices.Clone(energy)
+	slices.Sort(sorted)
+	floor := sorted[int(float64(len(sorted)-1)*vadFloorPercentile)]
+	threshold := max(floor*vadThresholdRatio, vadMinThreshold)
+
+	var segments []speechSegment
+	start := -1
+	for frame := 0; frame <= len(energy); frame++ {
+		speech := frame < len(energy) && energy[frame] >= threshold
+		if speech && start < 0 {
+			start = frame
+		} else if !speech && start >= 0 {
+			segment := speechSegment{
+				Start: time.Duration(start) * energyFrame,
+				End:   time.Duration(frame) * energyFrame,
+			}
+			if n := len(segments); n > 0 && segment.Start-segments[n-1].End < vadMinPause {
+				segments[n-1].End = segment.End
+			} else {
+				segments = append(segments, segment)
+			}
+			start = -1
+		}
+	}
+
+	return slices.DeleteFunc(segments, func(s speechSegment) bool {
+		return s.End-s.Start < vadMinSpeech
+	})
+}
+
+// wavSpeech detects the speech of a WAV file and returns it with the length
+// of the recording.
+func wavSpeech(path string) ([]speechSegment, time.Duration, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open audio file: %w", err)
+	}
+	defer file.Close()
+
+	format, err := parseWAVHeader(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	energy, err := wavEnergy(file, format)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return detectSpeech(energy), format.Duration(), nil
+}
@@ -0,0 +1,300 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// probeTimeout bounds ffprobe, which only reads container headers
+const probeTimeout = 30 * time.Second
+
+// videoFileTypes are the containers whose streams are extracted with ffmpeg
+var videoFileTypes = map[string]bool{"mp4": true, "m4v": true, "mkv": true, "mov": true}
+
+// textSubtitleCodecs are the subtitle codecs ffmpeg can convert to SRT;
+// image based ones such as PGS and VobSub would need OCR.
+var textSubtitleCodecs = map[string]bool{
+	"subrip": true, "srt": true, "ass": true, "ssa": true, "mov_text": true, "webvtt": true, "text": true,
+}
+
+// iso6392Codes maps the three letter language tags of video containers to
+// the codes used for languages.
+var iso6392Codes = map[string]string{
+	"eng": "en", "chi": "zh", "zho": "zh", "jpn": "ja", "kor": "ko", "tha": "th", "ind": "id",
+	"may": "ms", "msa": "ms", "vie": "vi", "hin": "hi", "spa": "es", "fre": "fr", "fra": "fr",
+	"ger": "de", "deu": "de", "ita": "it", "por": "pt", "dut": "nl", "nld": "nl", "swe": "sv",
+	"pol": "pl", "tur": "tr", "rus": "ru", "ukr": "uk", "ara": "ar", "per": "fa", "fas": "fa",
+	"heb": "he", "gre": "el", "ell": "el", "ben": "bn", "tam": "ta", "cze": "cs", "ces": "cs",
+	"dan": "da", "fin": "fi", "hun": "hu", "nor": "no", "rum": "ro", "ron": "ro", "fil": "tl",
+	"tgl": "tl",
+}
+
+// MediaStream is an audio or text subtitle stream of a video file.
+type MediaStream struct {
+	// Index is the stream's index in the container, as ffmpeg numbers them
+	Index int    `json:"index"`
+	Type  string `json:"type"`
+	Codec string `json:"codec"`
+	// Language is a language code from the stream tags, empty if untagged
+	Language string `json:"language"`
+	Title    string `json:"title"`
+	Channels int    `json:"channels"`
+	Default  bool   `json:"default"`
+}
+
+// mediaProbe is what ffprobe tells about a file.
+type mediaProbe struct {
+	Duration time.Duration
+	Streams  []MediaStream
+	// Framerate, Width and Height are those of the first video stream, zero
+	// for audio files
+	Framerate float64
+	Width     int
+	Height    int
+}
+
+// ffprobeOutput is the part of ffprobe's JSON output that is used.
+type ffprobeOutput struct {
+	Streams []struct {
+		Index       int    `json:"index"`
+		CodecType   string `json:"codec_type"`
+		CodecName   string `json:"codec_name"`
+		Channels    int    `json:"channels"`
+		Disposition struct {
+			Default     int `json:"default"`
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+		Tags struct {
+			Language string `json:"language"`
+			Title    string `json:"title"`
+		} `json:"tags"`
+		// Frame rates are fractions such as 24000/1001
+		AvgFrameRate string `json:"avg_frame_rate"`
+		RFrameRate   string `json:"r_frame_rate"`
+		Width        int    `json:"width"`
+		Height       int    `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+func isVideoFileType(fileType string) bool {
+	return videoFileTypes[strings.ToLower(fileType)]
+}
+
+// probeMedia lists the audio and text subtitle streams of a file with ffprobe.
+func probeMedia(ctx context.Context, path string) (mediaProbe, error) {
+	ffprobe, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return mediaProbe{}, errors.New("ffprobe must be installed to read video files")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, ffprobe, "-v", "error", "-print_format", "json", "-show_streams", "-show_format", path)
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return mediaProbe{}, fmt.Errorf("failed to probe media: %w: %s", err, lastLines(string(exitErr.Stderr), 5))
+		}
+		return mediaProbe{}, fmt.Errorf("failed to probe media: %w", err)
+	}
+
+	var parsed ffprobeOutput
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return mediaProbe{}, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	var probe mediaProbe
+	if duration, err := strconv.ParseFloat(parsed.Format.Duration, 64); err == nil {
+		probe.Duration = seconds(duration)
+	}
+	for _, stream := range parsed.Streams {
+		// Cover art is stored as a one frame video stream
+		if stream.CodecType == "video" && stream.Disposition.AttachedPic == 0 && probe.Width == 0 {
+			probe.Framerate = parseFrameRate(stream.AvgFrameRate)
+			if probe.Framerate == 0 {
+				probe.Framerate = parseFrameRate(stream.RFrameRate)
+			}
+			probe.Width, probe.Height = stream.Width, stream.Height
+		}
+		if stream.CodecType != "audio" && (stream.CodecType != "subtitle" || !textSubtitleCodecs[stream.CodecName]) {
+			continue
+		}
+		language := strings.ToLower(stream.Tags.Language)
+		if code, ok := iso6392Codes[language]; ok {
+			language = code
+		} else if len(language) != 2 {
+			language = ""
+		}
+		probe.Streams = append(probe.Streams, MediaStream{
+			Index:    stream.Index,
+			Type:     stream.CodecType,
+			Codec:    stream.CodecName,
+			Language: language,
+			Title:    stream.Tags.Title,
+			Channels: stream.Channels,
+			Default:  stream.Disposition.Default == 1,
+		})
+	}
+
+	return probe, nil
+}
+
+// parseFrameRate reads a frame rate as ffprobe writes it, 24000/1001 or 25,
+// returning zero if it is unknown.
+func parseFrameRate(rate string) float64 {
+	num, den, found := strings.Cut(rate, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	if !found {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return n / d
+}
+
+// defaultAudio returns the audio stream a player would pick: the one marked
+// default, or else the first.
+func (p mediaProbe) defaultAudio() (MediaStream, bool) {
+	var first *MediaStream
+	for i, stream := range p.Streams {
+		if stream.Type != "audio" {
+			continue
+		}
+		if stream.Default {
+			return stream, true
+		}
+		if first == nil {
+			first = &p.Streams[i]
+		}
+	}
+	if first == nil {
+		return MediaStream{}, false
+	}
+	return *first, true
+}
+
+// ProbeMedia lists the streams of an uploaded video file that can be queued:
+// audio to transcribe and text subtitles to import.
+func (mq *MovieQueue) ProbeMedia(media StoredMedia) ([]MediaStream, error) {
+	path, _, err := findMedia(media.Hash, media.FileType)
+	if err != nil {
+		return nil, err
+	}
+
+	probe, err := probeMedia(context.Background(), path)
+	if err != nil {
+		return nil, err
+	}
+	if len(probe.Streams) == 0 {
+		return nil, errors.New("the file has no audio or text subtitle streams")
+	}
+
+	return probe.Streams, nil
+}
+
+// findStream returns the stream of a probed file with the given index and type.
+func findStream(ctx context.Context, path string, index int, streamType string) (MediaStream, error) {
+	probe, err := probeMedia(ctx, path)
+	if err != nil {
+		return MediaStream{}, err
+	}
+	for _, stream := range probe.Streams {
+		if stream.Index == index && stream.Type == streamType {
+			return stream, nil
+		}
+	}
+	return MediaStream{}, fmt.Errorf("stream %d is not a usable %s stream", index, streamType)
+}
+
+// defaultAudioStream returns the index of the audio stream a player would
+// pick: the one marked default, or else the first.
+func defaultAudioStream(ctx context.Context, path string) (int, error) {
+	probe, err := probeMedia(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	stream, ok := probe.defaultAudio()
+	if !ok {
+		return 0, errors.New("the file has no audio stream")
+	}
+	return stream.Index, nil
+}
+
+// extractAudioStream decodes one audio stream of a video file to 16 kHz mono
+// WAV for transcription. The caller removes the returned file.
+func extractAudioStream(ctx context.Context, path string, index int) (string, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return "", errors.New("ffmpeg must be installed to transcribe video files")
+	}
+
+	tmpFile, err := os.CreateTemp("", "audio-*.wav")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpFile.Close()
+
+	cmd := exec.CommandContext(ctx, ffmpeg, "-y", "-v", "error", "-i", path, "-map", fmt.Sprintf("0:%d", index),
+		"-vn", "-ac", "1", "-ar", fmt.Sprint(chunkSampleRate), "-c:a", "pcm_s16le", tmpFile.Name())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("failed to extract audio stream %d: %w: %s", index, err, lastLines(string(output), 5))
+	}
+
+	return tmpFile.Name(), nil
+}
+
+// extractSubtitleStream converts one text subtitle stream of a video file to SRT.
+func extractSubtitleStream(ctx context.Context, path string, index int) (string, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return "", errors.New("ffmpeg must be installed to read subtitles from video files")
+	}
+
+	cmd := exec.CommandContext(ctx, ffmpeg, "-v", "error", "-i", path, "-map", fmt.Sprintf("0:%d", index),
+		"-c:s", "srt", "-f", "srt", "-")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("failed to extract subtitle stream %d: %w: %s", index, err,
+				lastLines(string(exitErr.Stderr), 5))
+		}
+		return "", fmt.Errorf("failed to extract subtitle stream %d: %w", index, err)
+	}
+	if len(parseSRT(string(output))) == 0 {
+		return "", fmt.Errorf("subtitle stream %d is empty", index)
+	}
+
+	return string(output), nil
+}
+
+// extractQueuedSubtitles reads the subtitle stream picked for a queue request
+// from its uploaded video file.
+func extractQueuedSubtitles(r AddToQueueRequest) (string, error) {
+	path, _, err := findMedia(r.MediaHash, r.FileType)
+	if err != nil {
+		return "", err
+	}
+	if _, err := findStream(context.Background(), path, r.StreamIndex, "subtitle"); err != nil {
+		return "", err
+	}
+	return extractSubtitleStream(context.Background(), path, r.StreamIndex)
+}
@@ -0,0 +1,244 @@
+package backend
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// peaksPerSecond is the resolution of cached peaks, the finest a waveform is drawn at
+	peaksPerSecond = 100
+	// maxWaveformPeaks bounds one request, a few screens wide
+	maxWaveformPeaks = 20000
+	// peaksMagic starts a peaks file, with a version number
+	peaksMagic = "PKS1"
+	// peaksHeaderSize is the magic and the duration in milliseconds
+	peaksHeaderSize = 8
+)
+
+// peaksDir holds the cached peaks of media files, by media hash
+var peaksDir = filepath.Join(mediaDir, "peaks")
+
+// peaksMu keeps two requests from decoding the same audio at once
+var peaksMu sync.Mutex
+
+// WaveformRequest asks for the waveform of a movie's audio between two
+// times, in about the given number of peaks.
+type WaveformRequest struct {
+	MovieID int   `json:"movie_id"`
+	FromMs  int64 `json:"from_ms"`
+	// ToMs is the end of the window, the end of the audio if zero
+	ToMs  int64 `json:"to_ms"`
+	Peaks int   `json:"peaks"`
+}
+
+// Waveform holds the lowest and highest sample, between -1 and 1, of each
+// equal part of a window of audio. Windows finer than peaksPerSecond get
+// fewer peaks than asked for.
+type Waveform struct {
+	FromMs     int64     `json:"from_ms"`
+	ToMs       int64     `json:"to_ms"`
+	DurationMs int64     `json:"duration_ms"`
+	Min        []float32 `json:"min"`
+	Max        []float32 `json:"max"`
+}
+
+// peaksPath is where the peaks of an audio stream are cached.
+func peaksPath(audio movieAudio) string {
+	name := audio.Hash
+	if isVideoFileType(audio.FileType) {
+		name = fmt.Sprintf("%s-%d", audio.Hash, audio.StreamIndex)
+	}
+	return filepath.Join(peaksDir, name+".peaks")
+}
+
+// writePeaks decodes a WAV file into peaks at peaksPerSecond: a header and
+// then the minimum and maximum of each part as int16 pairs.
+func writePeaks(wavPath string, w io.Writer) error {
+	file, err := os.Open(wavPath)
+	if err != nil {
+		return fmt.Errorf("failed to open audio file: %w", err)
+	}
+	defer file.Close()
+
+	format, err := parseWAVHeader(file)
+	if err != nil {
+		return err
+	}
+	reader, err := newWAVFrameReader(file, format, 0, format.Duration())
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, peaksHeaderSize)
+	copy(header, peaksMagic)
+	binary.LittleEndian.PutUint32(header[4:], uint32(format.Duration().Milliseconds()))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	pair := make([]byte, 4)
+	lo, hi, count := 0.0, 0.0, 0
+	flush := func() error {
+		binary.LittleEndian.PutUint16(pair[0:], uint16(int16(math.Round(lo*32767))))
+		binary.LittleEndian.PutUint16(pair[2:], uint16(int16(math.Round(hi*32767))))
+		lo, hi, count = 0, 0, 0
+		_, err := w.Write(pair)
+		return err
+	}
+	// Parts are cut by time, so rates that are not a multiple of
+	// peaksPerSecond do not drift
+	bin := int64(0)
+	for frame := int64(0); ; frame++ {
+		sample, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read WAV samples: %w", err)
+		}
+		if next := frame * peaksPerSecond / int64(format.SampleRate); next != bin && count > 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			bin = next
+		}
+		sample = max(min(sample, 1), -1)
+		lo, hi = min(lo, sample), max(hi, sample)
+		count++
+	}
+	if count > 0 {
+		return flush()
+	}
+	return nil
+}
+
+// cachedPeaks returns the peaks file of an audio stream, decoding the audio
+// on first use.
+func cachedPeaks(ctx context.Context, audio movieAudio) (string, error) {
+	path := peaksPath(audio)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	peaksMu.Lock()
+	defer peaksMu.Unlock()
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	wavPath, cleanup, err := audio.wav(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	if err := os.MkdirAll(peaksDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create peaks directory: %w", err)
+	}
+	tmpFile, err := os.CreateTemp(peaksDir, "peaks-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create peaks file: %w", err)
+	}
+	w := bufio.NewWriter(tmpFile)
+	err = writePeaks(wavPath, w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("failed to write peaks: %w", err)
+	}
+	return path, nil
+}
+
+// readWaveform downsamples the cached peaks between two times.
+func readWaveform(path string, fromMs int64, toMs int64, peaks int) (Waveform, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Waveform{}, fmt.Errorf("failed to open peaks: %w", err)
+	}
+	defer file.Close()
+
+	header := make([]byte, peaksHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil || string(header[:4]) != peaksMagic {
+		return Waveform{}, errors.New("invalid peaks file")
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return Waveform{}, fmt.Errorf("failed to read peaks: %w", err)
+	}
+	bins := (info.Size() - peaksHeaderSize) / 4
+
+	waveform := Waveform{DurationMs: int64(binary.LittleEndian.Uint32(header[4:]))}
+	if toMs <= 0 || toMs > waveform.DurationMs {
+		toMs = waveform.DurationMs
+	}
+	waveform.FromMs, waveform.ToMs = max(fromMs, 0), toMs
+	waveform.Min, waveform.Max = []float32{}, []float32{}
+
+	msPerBin := int64(time.Second/time.Millisecond) / peaksPerSecond
+	first := min(waveform.FromMs/msPerBin, bins)
+	last := min((waveform.ToMs+msPerBin-1)/msPerBin, bins)
+	if last <= first {
+		return waveform, nil
+	}
+
+	data := make([]byte, (last-first)*4)
+	if _, err := file.ReadAt(data, peaksHeaderSize+first*4); err != nil {
+		return Waveform{}, fmt.Errorf("failed to read peaks: %w", err)
+	}
+
+	n := last - first
+	peaks = int(min(int64(peaks), n))
+	for i := range peaks {
+		from, to := int64(i)*n/int64(peaks), int64(i+1)*n/int64(peaks)
+		lo, hi := int16(math.MaxInt16), int16(math.MinInt16)
+		for bin := from; bin < to; bin++ {
+			lo = min(lo, int16(binary.LittleEndian.Uint16(data[bin*4:])))
+			hi = max(hi, int16(binary.LittleEndian.Uint16(data[bin*4+2:])))
+		}
+		waveform.Min = append(waveform.Min, float32(lo)/32767)
+		waveform.Max = append(waveform.Max, float32(hi)/32767)
+	}
+	return waveform, nil
+}
+
+// GetWaveform returns the waveform of a movie's audio for a window of the
+// timeline. The audio is decoded once, WAV natively and other formats with
+// ffmpeg, and its peaks are cached on disk.
+func (s Subtitle) GetWaveform(req WaveformRequest) (Waveform, error) {
+	if req.Peaks <= 0 || req.Peaks > maxWaveformPeaks {
+		return Waveform{}, fmt.Errorf("peaks must be between 1 and %d", maxWaveformPeaks)
+	}
+	if req.ToMs > 0 && req.ToMs <= req.FromMs {
+		return Waveform{}, errors.New("the window ends before it starts")
+	}
+
+	ctx := context.Background()
+	audio, err := findMovieAudio(ctx, req.MovieID)
+	if err != nil {
+		return Waveform{}, err
+	}
+	path, err := cachedPeaks(ctx, audio)
+	if err != nil {
+		return Waveform{}, err
+	}
+
+	return readWaveform(path, req.FromMs, req.ToMs, req.Peaks)
+}
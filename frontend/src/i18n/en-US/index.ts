@@ -162,5 +162,196 @@ export default {
 
   // Export Subtitle Component
   'Please select a language': 'Please select a language',
-  'Subtitles have been exported successfully.': 'Subtitles have been exported successfully.'
+  'Subtitles have been exported successfully.': 'Subtitles have been exported successfully.',
+
+  // Translation Gaps
+  'Translation Incomplete': 'Translation Incomplete',
+  '{count} subtitles untranslated': '{count} subtitles untranslated',
+  'Retry Translation': 'Retry Translation',
+  'Accept Translation Gaps': 'Accept Translation Gaps',
+  'Translation gaps accepted': 'Translation gaps accepted',
+  'Failed to accept translation gaps': 'Failed to accept translation gaps',
+  'Translation queued for retry': 'Translation queued for retry',
+  'Failed to retry translation': 'Failed to retry translation',
+
+  // Settings
+  'API Limits': 'API Limits',
+  'Requests per minute': 'Requests per minute',
+  'Tokens per minute': 'Tokens per minute',
+  'Max retries': 'Max retries',
+
+  // Usage and Cost
+  'Estimate Cost': 'Estimate Cost',
+  'Estimated cost: ${cost}': 'Estimated cost: ${cost}',
+  'Failed to estimate cost': 'Failed to estimate cost',
+  'API cost: ${cost}': 'API cost: ${cost}',
+
+  // Translation Quality
+  'Check translation quality': 'Check translation quality',
+  '{count} subtitles flagged for review': '{count} subtitles flagged for review',
+  'Failed to check translation quality': 'Failed to check translation quality',
+  'Quality score': 'Quality score',
+  'Mark as reviewed': 'Mark as reviewed',
+  'Translation Quality Check': 'Translation Quality Check',
+  'Method': 'Method',
+  'Minimum score': 'Minimum score',
+
+  // Subtitle Limits
+  'Subtitle Limits': 'Subtitle Limits',
+  'Max characters per line': 'Max characters per line',
+  'Max lines': 'Max lines',
+  'Max characters per second': 'Max characters per second',
+  '{count} subtitles exceed the length limits': '{count} subtitles exceed the length limits',
+
+  // Pivot Translation
+  'Translated via {languages}': 'Translated via {languages}',
+  'Pivot Language': 'Pivot Language',
+  'Translate to this language first and from it to the others': 'Translate to this language first and from it to the others',
+
+  // Selective Translation
+  'From Sl No': 'From Sl No',
+  'To Sl No': 'To Sl No',
+  'Instruction (optional)': 'Instruction (optional)',
+  'e.g. make it more casual': 'e.g. make it more casual',
+  'Overwrite existing translations': 'Overwrite existing translations',
+  'Translate Selection': 'Translate Selection',
+
+  // Translation Candidates
+  '{count} candidates': '{count} candidates',
+  'Over the subtitle limits': 'Over the subtitle limits',
+  'Candidates': 'Candidates',
+  'Generate Candidates': 'Generate Candidates',
+
+  // Translation Jobs
+  'Translation cancelled': 'Translation cancelled',
+  'Translation Cancelled': 'Translation Cancelled',
+  'Cancel Translation': 'Cancel Translation',
+  'Failed to cancel translation': 'Failed to cancel translation',
+  '{done} of {total} subtitles': '{done} of {total} subtitles',
+  'about {seconds}s left': 'about {seconds}s left',
+
+  // Translation Cache
+  'Translation Cache': 'Translation Cache',
+  '{entries} cached translations, used {hits} times': '{entries} cached translations, used {hits} times',
+  'this session: {hits} hits, {misses} misses': 'this session: {hits} hits, {misses} misses',
+  'Older than (days)': 'Older than (days)',
+  'All models': 'All models',
+  'Model': 'Model',
+  'Purge Cache': 'Purge Cache',
+  '{count} from cache': '{count} from cache',
+
+  // Transcription Cues
+  'Transcription Cues': 'Transcription Cues',
+  'Max cue duration (ms)': 'Max cue duration (ms)',
+  'Min gap between cues (ms)': 'Min gap between cues (ms)',
+  'Pause starting a new cue (ms)': 'Pause starting a new cue (ms)',
+
+  // Transcriber
+  'Transcriber': 'Transcriber',
+  'Default transcriber': 'Default transcriber',
+  'Local server': 'Local server',
+  'Local server URL': 'Local server URL',
+  'Server model': 'Server model',
+  'Local program': 'Local program',
+  'Program arguments': 'Program arguments',
+
+  // Language detection
+  'Detect automatically': 'Detect automatically',
+  'Language mismatch': '{name}: declared {declared} but the text looks like {detected}',
+  'Detected language': 'Detected language: {language}',
+
+  // Video files
+  'Video Files': 'Video Files',
+  'Select Video files': 'Select Video files',
+  'Only mp4, mkv, mov': 'Only mp4, mkv, mov',
+  'Stream': 'Stream',
+  'Audio is transcribed, subtitles are imported': 'Audio is transcribed, subtitles are imported',
+  'Source language is required': 'Source language is required',
+  'Audio': 'Audio',
+  '{count} channels': '{count} channels',
+  'Failed to read video file': 'Failed to read {name}',
+
+  // Speakers
+  'Speaker': 'Speaker',
+  'Speakers': 'Speakers',
+  'Speaker updated': 'Speaker updated',
+  'Failed to update speaker': 'Failed to update speaker',
+  'Rename': 'Rename',
+  'Failed to rename speaker': 'Failed to rename speaker',
+  '{count} subtitles updated': '{count} subtitles updated',
+  '{count} subtitles': '{count} subtitles',
+  'No speakers yet. Transcribe with a diarizing program or type a speaker next to a subtitle.': 'No speakers yet. Transcribe with a diarizing program or type a speaker next to a subtitle.',
+  'Format': 'Format',
+  'Transcript': 'Transcript',
+  'Speakers are included in WebVTT, ASS and transcripts': 'Speakers are included in WebVTT, ASS and transcripts',
+
+  // Audio translation
+  'Also create English subtitles from the audio': 'Also create English subtitles from the audio',
+  'English subtitles are translated from the audio instead of the transcript': 'English subtitles are translated from the audio instead of the transcript',
+
+  // Timing
+  'Timing': 'Timing',
+  'Snap to speech': 'Snap to speech',
+  'Moves the start and end of each subtitle to where speech starts and ends in the audio of the movie.': 'Moves the start and end of each subtitle to where speech starts and ends in the audio of the movie.',
+  'Tolerance': 'Tolerance',
+  'Preview': 'Preview',
+  'Apply': 'Apply',
+  'Before': 'Before',
+  'After': 'After',
+  '{count} subtitles moved': '{count} subtitles moved',
+  '{moved} of {checked} subtitles moved': '{moved} of {checked} subtitles moved',
+  '{moved} of {checked} subtitles would move': '{moved} of {checked} subtitles would move',
+  'Failed to snap subtitles to speech': 'Failed to snap subtitles to speech',
+
+  // Auto-sync
+  'Auto-sync': 'Auto-sync',
+  'Fits subtitles made for another cut or release to the audio: finds the offset and frame rate change, and follows drift along the movie. All languages are moved together.': 'Fits subtitles made for another cut or release to the audio: finds the offset and frame rate change, and follows drift along the movie. All languages are moved together.',
+  'Maximum offset': 'Maximum offset',
+  'Offset {offset}, speed {scale}, {anchors} drift corrections': 'Offset {offset}, speed {scale}, {anchors} drift corrections',
+  'Failed to sync subtitles to the audio': 'Failed to sync subtitles to the audio',
+
+  // Preview
+  'Failed to render subtitles': 'Failed to render subtitles',
+  'The media of this movie cannot be played. Link an audio or video file the player supports.': 'The media of this movie cannot be played. Link an audio or video file the player supports.',
+
+  // Movie media
+  'Media': 'Media',
+  'Media linked successfully': 'Media linked successfully',
+  '{count} audio channels': '{count} audio channels',
+  'No audio': 'No audio',
+  'The primary file is played in the preview, used to sync timing and checked against in timing validation and exports.': 'The primary file is played in the preview, used to sync timing and checked against in timing validation and exports.',
+  'No media linked to this movie': 'No media linked to this movie',
+  'Primary': 'Primary',
+  'Make primary': 'Make primary',
+  'Unlink': 'Unlink',
+  'Audio or video file': 'Audio or video file',
+  'Link': 'Link',
+  'MicroDVD is timed in the frames of the movie\'s linked video': 'MicroDVD is timed in the frames of the movie\'s linked video',
+  'Invalid time': 'Invalid time',
+  'Ends before it starts': 'Ends before it starts',
+  'Ends after the media': 'Ends after the media',
+  'Issue': 'Issue',
+  'Failed to validate timing': 'Failed to validate timing',
+  'Validate timing': 'Validate timing',
+  'Finds subtitles with invalid times, and subtitles that run past the end of the movie\'s primary media.': 'Finds subtitles with invalid times, and subtitles that run past the end of the movie\'s primary media.',
+  'Check': 'Check',
+  '{issues} of {checked} subtitles have timing issues, checked against media of {duration}': '{issues} of {checked} subtitles have timing issues, checked against media of {duration}',
+  '{issues} of {checked} subtitles have timing issues; link media to check them against its duration': '{issues} of {checked} subtitles have timing issues; link media to check them against its duration',
+  'With a linked video, times are aligned to its frames.': 'With a linked video, times are aligned to its frames.',
+
+  // Movie metadata
+  'Year': 'Year',
+  'Series': 'Series',
+  'Season': 'Season',
+  'Episode': 'Episode',
+  'Genre': 'Genre',
+  'Runtime': 'Runtime',
+  'min': 'min',
+  'Notes': 'Notes',
+  'Details': 'Details',
+  'IMDb ID': 'IMDb ID',
+  'TMDb ID': 'TMDb ID',
+  'IMDb / TMDb': 'IMDb / TMDb',
+  'More filters': 'More filters',
+  'IMDb or TMDb ID': 'IMDb or TMDb ID',
 };
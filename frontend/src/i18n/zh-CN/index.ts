@@ -161,5 +161,196 @@ export default {
 
   // Export Subtitle Component
   'Please select a language': '请选择语言',
-  'Subtitles have been exported successfully.': '字幕已成功导出。'
+  'Subtitles have been exported successfully.': '字幕已成功导出。',
+
+  // Translation Gaps
+  'Translation Incomplete': '翻译未完成',
+  '{count} subtitles untranslated': '{count} 条字幕未翻译',
+  'Retry Translation': '重试翻译',
+  'Accept Translation Gaps': '接受未翻译字幕',
+  'Translation gaps accepted': '已接受未翻译字幕',
+  'Failed to accept translation gaps': '接受未翻译字幕失败',
+  'Translation queued for retry': '翻译已加入重试队列',
+  'Failed to retry translation': '重试翻译失败',
+
+  // Settings
+  'API Limits': 'API 限制',
+  'Requests per minute': '每分钟请求数',
+  'Tokens per minute': '每分钟令牌数',
+  'Max retries': '最大重试次数',
+
+  // Usage and Cost
+  'Estimate Cost': '估算费用',
+  'Estimated cost: ${cost}': '预计费用：${cost}',
+  'Failed to estimate cost': '估算费用失败',
+  'API cost: ${cost}': 'API 费用：${cost}',
+
+  // Translation Quality
+  'Check translation quality': '检查翻译质量',
+  '{count} subtitles flagged for review': '{count} 条字幕需要审核',
+  'Failed to check translation quality': '检查翻译质量失败',
+  'Quality score': '质量评分',
+  'Mark as reviewed': '标记为已审核',
+  'Translation Quality Check': '翻译质量检查',
+  'Method': '方法',
+  'Minimum score': '最低评分',
+
+  // Subtitle Limits
+  'Subtitle Limits': '字幕限制',
+  'Max characters per line': '每行最多字符数',
+  'Max lines': '最多行数',
+  'Max characters per second': '每秒最多字符数',
+  '{count} subtitles exceed the length limits': '{count} 条字幕超出长度限制',
+
+  // Pivot Translation
+  'Translated via {languages}': '经由{languages}翻译',
+  'Pivot Language': '中转语言',
+  'Translate to this language first and from it to the others': '先翻译为此语言，再由其翻译为其他语言',
+
+  // Selective Translation
+  'From Sl No': '起始序号',
+  'To Sl No': '结束序号',
+  'Instruction (optional)': '翻译要求（可选）',
+  'e.g. make it more casual': '例如：更口语化一些',
+  'Overwrite existing translations': '覆盖已有翻译',
+  'Translate Selection': '翻译所选',
+
+  // Translation Candidates
+  '{count} candidates': '{count} 个候选',
+  'Over the subtitle limits': '超出字幕限制',
+  'Candidates': '候选数',
+  'Generate Candidates': '生成候选翻译',
+
+  // Translation Jobs
+  'Translation cancelled': '翻译已取消',
+  'Translation Cancelled': '翻译已取消',
+  'Cancel Translation': '取消翻译',
+  'Failed to cancel translation': '取消翻译失败',
+  '{done} of {total} subtitles': '已完成 {done} / {total} 条字幕',
+  'about {seconds}s left': '约剩 {seconds} 秒',
+
+  // Translation Cache
+  'Translation Cache': '翻译缓存',
+  '{entries} cached translations, used {hits} times': '已缓存 {entries} 条翻译，使用 {hits} 次',
+  'this session: {hits} hits, {misses} misses': '本次会话：命中 {hits} 次，未命中 {misses} 次',
+  'Older than (days)': '早于（天）',
+  'All models': '所有模型',
+  'Model': '模型',
+  'Purge Cache': '清除缓存',
+  '{count} from cache': '{count} 条来自缓存',
+
+  // Transcription Cues
+  'Transcription Cues': '字幕切分',
+  'Max cue duration (ms)': '最长字幕时长（毫秒）',
+  'Min gap between cues (ms)': '字幕最小间隔（毫秒）',
+  'Pause starting a new cue (ms)': '开始新字幕的停顿（毫秒）',
+
+  // Transcriber
+  'Transcriber': '转录引擎',
+  'Default transcriber': '默认转录引擎',
+  'Local server': '本地服务器',
+  'Local server URL': '本地服务器地址',
+  'Server model': '服务器模型',
+  'Local program': '本地程序',
+  'Program arguments': '程序参数',
+
+  // Language detection
+  'Detect automatically': '自动检测',
+  'Language mismatch': '{name}：声明为 {declared}，但检测到 {detected}',
+  'Detected language': '检测到的语言：{language}',
+
+  // Video files
+  'Video Files': '视频文件',
+  'Select Video files': '选择视频文件',
+  'Only mp4, mkv, mov': '仅限 mp4、mkv、mov',
+  'Stream': '流',
+  'Audio is transcribed, subtitles are imported': '音频将被转录，字幕将被导入',
+  'Source language is required': '源语言为必填项',
+  'Audio': '音频',
+  '{count} channels': '{count} 声道',
+  'Failed to read video file': '无法读取 {name}',
+
+  // Speakers
+  'Speaker': '说话人',
+  'Speakers': '说话人',
+  'Speaker updated': '说话人已更新',
+  'Failed to update speaker': '更新说话人失败',
+  'Rename': '重命名',
+  'Failed to rename speaker': '重命名说话人失败',
+  '{count} subtitles updated': '已更新 {count} 条字幕',
+  '{count} subtitles': '{count} 条字幕',
+  'No speakers yet. Transcribe with a diarizing program or type a speaker next to a subtitle.': '暂无说话人。请使用支持说话人分离的程序转录，或在字幕旁输入说话人。',
+  'Format': '格式',
+  'Transcript': '文字稿',
+  'Speakers are included in WebVTT, ASS and transcripts': 'WebVTT、ASS 和文字稿中包含说话人',
+
+  // Audio translation
+  'Also create English subtitles from the audio': '同时从音频生成英文字幕',
+  'English subtitles are translated from the audio instead of the transcript': '英文字幕直接从音频翻译，而不是从转录文本翻译',
+
+  // Timing
+  'Timing': '时间轴',
+  'Snap to speech': '吸附到语音',
+  'Moves the start and end of each subtitle to where speech starts and ends in the audio of the movie.': '将每条字幕的开始和结束时间移动到影片音频中语音开始和结束的位置。',
+  'Tolerance': '容差',
+  'Preview': '预览',
+  'Apply': '应用',
+  'Before': '之前',
+  'After': '之后',
+  '{count} subtitles moved': '已移动 {count} 条字幕',
+  '{moved} of {checked} subtitles moved': '{checked} 条字幕中已移动 {moved} 条',
+  '{moved} of {checked} subtitles would move': '{checked} 条字幕中将移动 {moved} 条',
+  'Failed to snap subtitles to speech': '字幕吸附到语音失败',
+
+  // Auto-sync
+  'Auto-sync': '自动同步',
+  'Fits subtitles made for another cut or release to the audio: finds the offset and frame rate change, and follows drift along the movie. All languages are moved together.': '将为其他剪辑版本或发行版制作的字幕与音频对齐：计算偏移和帧率变化，并跟踪整部影片中的漂移。所有语言一起移动。',
+  'Maximum offset': '最大偏移',
+  'Offset {offset}, speed {scale}, {anchors} drift corrections': '偏移 {offset}，速度 {scale}，{anchors} 处漂移校正',
+  'Failed to sync subtitles to the audio': '字幕与音频同步失败',
+
+  // Preview
+  'Failed to render subtitles': '字幕渲染失败',
+  'The media of this movie cannot be played. Link an audio or video file the player supports.': '无法播放此影片的媒体。请关联播放器支持的音频或视频文件。',
+
+  // Movie media
+  'Media': '媒体',
+  'Media linked successfully': '媒体关联成功',
+  '{count} audio channels': '{count} 个音频声道',
+  'No audio': '无音频',
+  'The primary file is played in the preview, used to sync timing and checked against in timing validation and exports.': '主文件用于预览播放、时间同步，以及时间校验和导出时的比对。',
+  'No media linked to this movie': '此影片尚未关联媒体',
+  'Primary': '主文件',
+  'Make primary': '设为主文件',
+  'Unlink': '取消关联',
+  'Audio or video file': '音频或视频文件',
+  'Link': '关联',
+  'MicroDVD is timed in the frames of the movie\'s linked video': 'MicroDVD 按影片关联视频的帧计时',
+  'Invalid time': '时间无效',
+  'Ends before it starts': '结束早于开始',
+  'Ends after the media': '结束晚于媒体',
+  'Issue': '问题',
+  'Failed to validate timing': '时间校验失败',
+  'Validate timing': '校验时间',
+  'Finds subtitles with invalid times, and subtitles that run past the end of the movie\'s primary media.': '查找时间无效的字幕，以及超出影片主媒体结尾的字幕。',
+  'Check': '检查',
+  '{issues} of {checked} subtitles have timing issues, checked against media of {duration}': '{checked} 条字幕中有 {issues} 条存在时间问题（已对照时长 {duration} 的媒体）',
+  '{issues} of {checked} subtitles have timing issues; link media to check them against its duration': '{checked} 条字幕中有 {issues} 条存在时间问题；关联媒体后可对照其时长检查',
+  'With a linked video, times are aligned to its frames.': '关联视频后，时间会对齐到视频帧。',
+
+  // Movie metadata
+  'Year': '年份',
+  'Series': '剧集',
+  'Season': '季',
+  'Episode': '集',
+  'Genre': '类型',
+  'Runtime': '片长',
+  'min': '分钟',
+  'Notes': '备注',
+  'Details': '详细信息',
+  'IMDb ID': 'IMDb ID',
+  'TMDb ID': 'TMDb ID',
+  'IMDb / TMDb': 'IMDb / TMDb',
+  'More filters': '更多筛选',
+  'IMDb or TMDb ID': 'IMDb 或 TMDb ID',
 };
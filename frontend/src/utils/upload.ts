@@ -0,0 +1,30 @@
+import {
+  BeginMediaUpload,
+  CancelMediaUpload,
+  FinishMediaUpload,
+  WriteMediaChunk,
+} from '../../wailsjs/go/backend/MovieQueue';
+
+// Media is sent in slices of this size so that large files never sit in memory whole
+const uploadChunkSize = 4 * 1024 * 1024;
+
+// uploadMedia stores a file in the media directory and returns what identifies it
+export const uploadMedia = async (file: File) => {
+  const id = await BeginMediaUpload(file.name.split('.').pop() || '');
+  try {
+    for (let offset = 0; offset < file.size; offset += uploadChunkSize) {
+      const bytes = new Uint8Array(
+        await file.slice(offset, offset + uploadChunkSize).arrayBuffer()
+      );
+      let binary = '';
+      for (let i = 0; i < bytes.length; i += 0x8000) {
+        binary += String.fromCharCode(...bytes.subarray(i, i + 0x8000));
+      }
+      await WriteMediaChunk(id, btoa(binary));
+    }
+    return await FinishMediaUpload(id);
+  } catch (error) {
+    await CancelMediaUpload(id);
+    throw error;
+  }
+};
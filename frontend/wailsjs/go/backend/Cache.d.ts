@@ -0,0 +1,7 @@
+// Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
+// This file is automatically generated. DO NOT EDIT
+import {backend} from '../models';
+
+export function GetCacheStats():Promise<backend.CacheStats>;
+
+export function PurgeCache(arg1:number,arg2:string):Promise<number>;
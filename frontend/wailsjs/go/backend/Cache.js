@@ -0,0 +1,11 @@
+// @ts-check
+// Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
+// This file is automatically generated. DO NOT EDIT
+
+export function GetCacheStats() {
+  return window['go']['backend']['Cache']['GetCacheStats']();
+}
+
+export function PurgeCache(arg1, arg2) {
+  return window['go']['backend']['Cache']['PurgeCache'](arg1, arg2);
+}
@@ -0,0 +1,7 @@
+// Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
+// This file is automatically generated. DO NOT EDIT
+import {backend} from '../models';
+
+export function CancelJob(arg1:number):Promise<void>;
+
+export function ListJobs():Promise<Array<backend.Job>>;
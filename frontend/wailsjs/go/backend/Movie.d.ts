@@ -2,12 +2,20 @@
 // This file is automatically generated. DO NOT EDIT
 import {backend} from '../models';
 
-export function CreateMovie(arg1:string,arg2:string,arg3:Record<string, string>):Promise<backend.Movie>;
+export function CreateMovie(arg1:string,arg2:string,arg3:Record<string, string>,arg4:backend.MovieMetadata):Promise<backend.Movie>;
 
 export function DeleteMovie(arg1:number):Promise<void>;
 
 export function GetMovieByID(arg1:number):Promise<backend.Movie>;
 
-export function ListMovies(arg1:string,arg2:backend.Pagination):Promise<backend.ListMoviesResponse>;
+export function LinkMedia(arg1:number,arg2:backend.StoredMedia):Promise<backend.MovieMedia>;
+
+export function ListMedia(arg1:number):Promise<Array<backend.MovieMedia>>;
+
+export function ListMovies(arg1:backend.MovieFilter,arg2:backend.Pagination):Promise<backend.ListMoviesResponse>;
+
+export function SetPrimaryMedia(arg1:number):Promise<void>;
+
+export function UnlinkMedia(arg1:number):Promise<void>;
 
 export function UpdateMovie(arg1:backend.Movie):Promise<void>;
@@ -2,8 +2,8 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function CreateMovie(arg1, arg2, arg3) {
-  return window['go']['backend']['Movie']['CreateMovie'](arg1, arg2, arg3);
+export function CreateMovie(arg1, arg2, arg3, arg4) {
+  return window['go']['backend']['Movie']['CreateMovie'](arg1, arg2, arg3, arg4);
 }
 
 export function DeleteMovie(arg1) {
@@ -14,10 +14,26 @@ export function GetMovieByID(arg1) {
   return window['go']['backend']['Movie']['GetMovieByID'](arg1);
 }
 
+export function LinkMedia(arg1, arg2) {
+  return window['go']['backend']['Movie']['LinkMedia'](arg1, arg2);
+}
+
+export function ListMedia(arg1) {
+  return window['go']['backend']['Movie']['ListMedia'](arg1);
+}
+
 export function ListMovies(arg1, arg2) {
   return window['go']['backend']['Movie']['ListMovies'](arg1, arg2);
 }
 
+export function SetPrimaryMedia(arg1) {
+  return window['go']['backend']['Movie']['SetPrimaryMedia'](arg1);
+}
+
+export function UnlinkMedia(arg1) {
+  return window['go']['backend']['Movie']['UnlinkMedia'](arg1);
+}
+
 export function UpdateMovie(arg1) {
   return window['go']['backend']['Movie']['UpdateMovie'](arg1);
 }
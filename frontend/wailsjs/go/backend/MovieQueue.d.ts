@@ -2,8 +2,24 @@
 // This file is automatically generated. DO NOT EDIT
 import {backend} from '../models';
 
+export function AcceptTranslationGaps(arg1:number):Promise<void>;
+
 export function AddToQueue(arg1:Array<backend.AddToQueueRequest>):Promise<void>;
 
+export function BeginMediaUpload(arg1:string):Promise<string>;
+
+export function CancelMediaUpload(arg1:string):Promise<void>;
+
 export function DeleteFromQueue(arg1:number):Promise<void>;
 
+export function DetectLanguages(arg1:Array<backend.AddToQueueRequest>):Promise<Array<backend.LanguageDetection>>;
+
+export function FinishMediaUpload(arg1:string):Promise<backend.StoredMedia>;
+
 export function ListQueue(arg1:string,arg2:backend.Pagination):Promise<backend.MovieQueueResponse>;
+
+export function ProbeMedia(arg1:backend.StoredMedia):Promise<Array<backend.MediaStream>>;
+
+export function RetryTranslation(arg1:number):Promise<void>;
+
+export function WriteMediaChunk(arg1:string,arg2:string):Promise<void>;
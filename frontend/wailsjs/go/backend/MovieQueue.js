@@ -2,14 +2,46 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AcceptTranslationGaps(arg1) {
+  return window['go']['backend']['MovieQueue']['AcceptTranslationGaps'](arg1);
+}
+
 export function AddToQueue(arg1) {
   return window['go']['backend']['MovieQueue']['AddToQueue'](arg1);
 }
 
+export function BeginMediaUpload(arg1) {
+  return window['go']['backend']['MovieQueue']['BeginMediaUpload'](arg1);
+}
+
+export function CancelMediaUpload(arg1) {
+  return window['go']['backend']['MovieQueue']['CancelMediaUpload'](arg1);
+}
+
 export function DeleteFromQueue(arg1) {
   return window['go']['backend']['MovieQueue']['DeleteFromQueue'](arg1);
 }
 
+export function DetectLanguages(arg1) {
+  return window['go']['backend']['MovieQueue']['DetectLanguages'](arg1);
+}
+
+export function FinishMediaUpload(arg1) {
+  return window['go']['backend']['MovieQueue']['FinishMediaUpload'](arg1);
+}
+
 export function ListQueue(arg1, arg2) {
   return window['go']['backend']['MovieQueue']['ListQueue'](arg1, arg2);
 }
+
+export function ProbeMedia(arg1) {
+  return window['go']['backend']['MovieQueue']['ProbeMedia'](arg1);
+}
+
+export function RetryTranslation(arg1) {
+  return window['go']['backend']['MovieQueue']['RetryTranslation'](arg1);
+}
+
+export function WriteMediaChunk(arg1, arg2) {
+  return window['go']['backend']['MovieQueue']['WriteMediaChunk'](arg1, arg2);
+}
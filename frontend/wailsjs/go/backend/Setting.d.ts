@@ -1,6 +1,27 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {backend} from '../models';
+
+export function GetAPILimits():Promise<backend.APILimits>;
 
 export function GetOpenAIKey():Promise<string>;
 
+export function GetQualitySettings():Promise<backend.QualitySettings>;
+
+export function GetSegmentationSettings():Promise<backend.SegmentationSettings>;
+
+export function GetSubtitleConstraints():Promise<backend.SubtitleConstraints>;
+
+export function GetTranscriberSettings():Promise<backend.TranscriberSettings>;
+
+export function SaveAPILimits(arg1:backend.APILimits):Promise<void>;
+
 export function SaveOpenAIKey(arg1:string):Promise<void>;
+
+export function SaveQualitySettings(arg1:backend.QualitySettings):Promise<void>;
+
+export function SaveSegmentationSettings(arg1:backend.SegmentationSettings):Promise<void>;
+
+export function SaveSubtitleConstraints(arg1:backend.SubtitleConstraints):Promise<void>;
+
+export function SaveTranscriberSettings(arg1:backend.TranscriberSettings):Promise<void>;
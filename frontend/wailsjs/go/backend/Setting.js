@@ -2,10 +2,50 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function GetAPILimits() {
+  return window['go']['backend']['Setting']['GetAPILimits']();
+}
+
 export function GetOpenAIKey() {
   return window['go']['backend']['Setting']['GetOpenAIKey']();
 }
 
+export function GetQualitySettings() {
+  return window['go']['backend']['Setting']['GetQualitySettings']();
+}
+
+export function GetSegmentationSettings() {
+  return window['go']['backend']['Setting']['GetSegmentationSettings']();
+}
+
+export function GetSubtitleConstraints() {
+  return window['go']['backend']['Setting']['GetSubtitleConstraints']();
+}
+
+export function GetTranscriberSettings() {
+  return window['go']['backend']['Setting']['GetTranscriberSettings']();
+}
+
+export function SaveAPILimits(arg1) {
+  return window['go']['backend']['Setting']['SaveAPILimits'](arg1);
+}
+
 export function SaveOpenAIKey(arg1) {
   return window['go']['backend']['Setting']['SaveOpenAIKey'](arg1);
 }
+
+export function SaveQualitySettings(arg1) {
+  return window['go']['backend']['Setting']['SaveQualitySettings'](arg1);
+}
+
+export function SaveSegmentationSettings(arg1) {
+  return window['go']['backend']['Setting']['SaveSegmentationSettings'](arg1);
+}
+
+export function SaveSubtitleConstraints(arg1) {
+  return window['go']['backend']['Setting']['SaveSubtitleConstraints'](arg1);
+}
+
+export function SaveTranscriberSettings(arg1) {
+  return window['go']['backend']['Setting']['SaveTranscriberSettings'](arg1);
+}
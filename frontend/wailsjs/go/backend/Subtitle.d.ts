@@ -2,12 +2,42 @@
 // This file is automatically generated. DO NOT EDIT
 import {backend} from '../models';
 
+export function AssignSpeaker(arg1:number,arg2:Array<number>,arg3:string):Promise<void>;
+
+export function AutoSync(arg1:backend.AutoSyncRequest):Promise<backend.SyncReport>;
+
+export function CheckTranslationQuality(arg1:number,arg2:string,arg3:string,arg4:string):Promise<backend.QualityReport>;
+
+export function ChooseCandidate(arg1:number,arg2:string,arg3:number):Promise<void>;
+
 export function ExportSubtitle(arg1:number,arg2:string):Promise<backend.ExportResponse>;
 
+export function ExportSubtitleAs(arg1:number,arg2:string,arg3:string):Promise<backend.ExportResponse>;
+
+export function GenerateCandidates(arg1:backend.CandidateRequest):Promise<Array<backend.Subtitle>>;
+
+export function GetSpeakers(arg1:number):Promise<Array<backend.SpeakerCount>>;
+
+export function GetSubtitleHistory(arg1:number):Promise<Array<backend.SubtitleHistory>>;
+
 export function GetSubtitlesByMovieID(arg1:number,arg2:backend.Pagination):Promise<backend.SubtitleResponse>;
 
+export function GetWaveform(arg1:backend.WaveformRequest):Promise<backend.Waveform>;
+
 export function ImportFromSRTFile(arg1:backend.Movie,arg2:string):Promise<void>;
 
-export function TranslateSubtitles(arg1:number,arg2:string,arg3:string):Promise<Array<string>>;
+export function RenameSpeaker(arg1:number,arg2:string,arg3:string):Promise<number>;
+
+export function RenderWebVTT(arg1:number,arg2:string):Promise<string>;
+
+export function ResolveQualityFlag(arg1:number,arg2:string):Promise<void>;
+
+export function SnapToSpeech(arg1:backend.SnapToSpeechRequest):Promise<backend.TimingReport>;
+
+export function TranslateSelection(arg1:backend.TranslateSelectionRequest):Promise<backend.TranslationReport>;
+
+export function TranslateSubtitles(arg1:number,arg2:string,arg3:string):Promise<backend.TranslationReport>;
 
 export function UpdateSubtitle(arg1:backend.Subtitle):Promise<void>;
+
+export function ValidateTiming(arg1:number):Promise<backend.TimingValidation>;
@@ -2,18 +2,74 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AssignSpeaker(arg1, arg2, arg3) {
+  return window['go']['backend']['Subtitle']['AssignSpeaker'](arg1, arg2, arg3);
+}
+
+export function AutoSync(arg1) {
+  return window['go']['backend']['Subtitle']['AutoSync'](arg1);
+}
+
+export function CheckTranslationQuality(arg1, arg2, arg3, arg4) {
+  return window['go']['backend']['Subtitle']['CheckTranslationQuality'](arg1, arg2, arg3, arg4);
+}
+
+export function ChooseCandidate(arg1, arg2, arg3) {
+  return window['go']['backend']['Subtitle']['ChooseCandidate'](arg1, arg2, arg3);
+}
+
 export function ExportSubtitle(arg1, arg2) {
   return window['go']['backend']['Subtitle']['ExportSubtitle'](arg1, arg2);
 }
 
+export function ExportSubtitleAs(arg1, arg2, arg3) {
+  return window['go']['backend']['Subtitle']['ExportSubtitleAs'](arg1, arg2, arg3);
+}
+
+export function GenerateCandidates(arg1) {
+  return window['go']['backend']['Subtitle']['GenerateCandidates'](arg1);
+}
+
+export function GetSpeakers(arg1) {
+  return window['go']['backend']['Subtitle']['GetSpeakers'](arg1);
+}
+
+export function GetSubtitleHistory(arg1) {
+  return window['go']['backend']['Subtitle']['GetSubtitleHistory'](arg1);
+}
+
 export function GetSubtitlesByMovieID(arg1, arg2) {
   return window['go']['backend']['Subtitle']['GetSubtitlesByMovieID'](arg1, arg2);
 }
 
+export function GetWaveform(arg1) {
+  return window['go']['backend']['Subtitle']['GetWaveform'](arg1);
+}
+
 export function ImportFromSRTFile(arg1, arg2) {
   return window['go']['backend']['Subtitle']['ImportFromSRTFile'](arg1, arg2);
 }
 
+export function RenameSpeaker(arg1, arg2, arg3) {
+  return window['go']['backend']['Subtitle']['RenameSpeaker'](arg1, arg2, arg3);
+}
+
+export function RenderWebVTT(arg1, arg2) {
+  return window['go']['backend']['Subtitle']['RenderWebVTT'](arg1, arg2);
+}
+
+export function ResolveQualityFlag(arg1, arg2) {
+  return window['go']['backend']['Subtitle']['ResolveQualityFlag'](arg1, arg2);
+}
+
+export function SnapToSpeech(arg1) {
+  return window['go']['backend']['Subtitle']['SnapToSpeech'](arg1);
+}
+
+export function TranslateSelection(arg1) {
+  return window['go']['backend']['Subtitle']['TranslateSelection'](arg1);
+}
+
 export function TranslateSubtitles(arg1, arg2, arg3) {
   return window['go']['backend']['Subtitle']['TranslateSubtitles'](arg1, arg2, arg3);
 }
@@ -21,3 +77,7 @@ export function TranslateSubtitles(arg1, arg2, arg3) {
 export function UpdateSubtitle(arg1) {
   return window['go']['backend']['Subtitle']['UpdateSubtitle'](arg1);
 }
+
+export function ValidateTiming(arg1) {
+  return window['go']['backend']['Subtitle']['ValidateTiming'](arg1);
+}
@@ -0,0 +1,15 @@
+// Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
+// This file is automatically generated. DO NOT EDIT
+import {backend} from '../models';
+
+export function EstimateQueueCost(arg1:Array<backend.AddToQueueRequest>):Promise<backend.CostEstimate>;
+
+export function EstimateTranslationCost(arg1:number,arg2:string,arg3:Array<string>):Promise<backend.CostEstimate>;
+
+export function GetModelPrices():Promise<Array<backend.ModelPrice>>;
+
+export function GetMovieUsage(arg1:number):Promise<backend.UsageSummary>;
+
+export function GetQueueUsage(arg1:number):Promise<backend.UsageSummary>;
+
+export function SaveModelPrice(arg1:backend.ModelPrice):Promise<void>;
@@ -0,0 +1,27 @@
+// @ts-check
+// Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
+// This file is automatically generated. DO NOT EDIT
+
+export function EstimateQueueCost(arg1) {
+  return window['go']['backend']['Usage']['EstimateQueueCost'](arg1);
+}
+
+export function EstimateTranslationCost(arg1, arg2, arg3) {
+  return window['go']['backend']['Usage']['EstimateTranslationCost'](arg1, arg2, arg3);
+}
+
+export function GetModelPrices() {
+  return window['go']['backend']['Usage']['GetModelPrices']();
+}
+
+export function GetMovieUsage(arg1) {
+  return window['go']['backend']['Usage']['GetMovieUsage'](arg1);
+}
+
+export function GetQueueUsage(arg1) {
+  return window['go']['backend']['Usage']['GetQueueUsage'](arg1);
+}
+
+export function SaveModelPrice(arg1) {
+  return window['go']['backend']['Usage']['SaveModelPrice'](arg1);
+}
@@ -1,5 +1,21 @@
 export namespace backend {
 	
+	export class APILimits {
+	    requests_per_minute: number;
+	    tokens_per_minute: number;
+	    max_retries: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new APILimits(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.requests_per_minute = source["requests_per_minute"];
+	        this.tokens_per_minute = source["tokens_per_minute"];
+	        this.max_retries = source["max_retries"];
+	    }
+	}
 	export class AddToQueueRequest {
 	    name: string;
 	    type: string;
@@ -7,6 +23,11 @@ export namespace backend {
 	    content: string;
 	    source_language: string;
 	    target_languages: string[];
+	    pivot_language: string;
+	    transcriber: string;
+	    media_hash: string;
+	    stream_index: number;
+	    translate_audio: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new AddToQueueRequest(source);
@@ -20,6 +41,138 @@ export namespace backend {
 	        this.content = source["content"];
 	        this.source_language = source["source_language"];
 	        this.target_languages = source["target_languages"];
+	        this.pivot_language = source["pivot_language"];
+	        this.transcriber = source["transcriber"];
+	        this.media_hash = source["media_hash"];
+	        this.stream_index = source["stream_index"];
+	        this.translate_audio = source["translate_audio"];
+	    }
+	}
+	export class AutoSyncRequest {
+	    movie_id: number;
+	    max_offset_seconds: number;
+	    dry_run: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new AutoSyncRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.movie_id = source["movie_id"];
+	        this.max_offset_seconds = source["max_offset_seconds"];
+	        this.dry_run = source["dry_run"];
+	    }
+	}
+	export class BatchError {
+	    ids: number[];
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ids = source["ids"];
+	        this.error = source["error"];
+	    }
+	}
+	export class CacheModel {
+	    model: string;
+	    entries: number;
+	    hits: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CacheModel(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.model = source["model"];
+	        this.entries = source["entries"];
+	        this.hits = source["hits"];
+	    }
+	}
+	export class CacheStats {
+	    entries: number;
+	    hits: number;
+	    models: CacheModel[];
+	    session_hits: number;
+	    session_misses: number;
+	    // Go type: time
+	    oldest_at?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new CacheStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.entries = source["entries"];
+	        this.hits = source["hits"];
+	        this.models = this.convertValues(source["models"], CacheModel);
+	        this.session_hits = source["session_hits"];
+	        this.session_misses = source["session_misses"];
+	        this.oldest_at = this.convertValues(source["oldest_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CandidateRequest {
+	    movie_id: number;
+	    source_language: string;
+	    target_language: string;
+	    ids: number[];
+	    count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CandidateRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.movie_id = source["movie_id"];
+	        this.source_language = source["source_language"];
+	        this.target_language = source["target_language"];
+	        this.ids = source["ids"];
+	        this.count = source["count"];
+	    }
+	}
+	export class CostEstimate {
+	    prompt_tokens: number;
+	    completion_tokens: number;
+	    audio_seconds: number;
+	    cost: number;
+	    warnings: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CostEstimate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.prompt_tokens = source["prompt_tokens"];
+	        this.completion_tokens = source["completion_tokens"];
+	        this.audio_seconds = source["audio_seconds"];
+	        this.cost = source["cost"];
+	        this.warnings = source["warnings"];
 	    }
 	}
 	export class ExportResponse {
@@ -34,6 +187,72 @@ export namespace backend {
 	        this.file_path = source["file_path"];
 	    }
 	}
+	export class JobProgress {
+	    job_id: number;
+	    movie_id: number;
+	    queue_id: number;
+	    language: string;
+	    done: number;
+	    total: number;
+	    eta_seconds: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new JobProgress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.job_id = source["job_id"];
+	        this.movie_id = source["movie_id"];
+	        this.queue_id = source["queue_id"];
+	        this.language = source["language"];
+	        this.done = source["done"];
+	        this.total = source["total"];
+	        this.eta_seconds = source["eta_seconds"];
+	    }
+	}
+	export class Job {
+	    id: number;
+	    kind: string;
+	    movie_id: number;
+	    queue_id: number;
+	    // Go type: time
+	    started_at: any;
+	    progress: Record<string, JobProgress>;
+	
+	    static createFrom(source: any = {}) {
+	        return new Job(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.kind = source["kind"];
+	        this.movie_id = source["movie_id"];
+	        this.queue_id = source["queue_id"];
+	        this.started_at = this.convertValues(source["started_at"], null);
+	        this.progress = this.convertValues(source["progress"], JobProgress, true);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
 	export class Language {
 	    id: number;
 	    name: string;
@@ -71,6 +290,26 @@ export namespace backend {
 		    return a;
 		}
 	}
+	export class LanguageDetection {
+	    name: string;
+	    declared: string;
+	    detected: string;
+	    confidence: number;
+	    mismatch: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new LanguageDetection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.declared = source["declared"];
+	        this.detected = source["detected"];
+	        this.confidence = source["confidence"];
+	        this.mismatch = source["mismatch"];
+	    }
+	}
 	export class Pagination {
 	    sortBy: string;
 	    descending: boolean;
@@ -91,11 +330,41 @@ export namespace backend {
 	        this.rowsNumber = source["rowsNumber"];
 	    }
 	}
+	export class MovieMetadata {
+	    year: number;
+	    series: string;
+	    season: number;
+	    episode: number;
+	    imdb_id: string;
+	    tmdb_id: number;
+	    runtime_minutes: number;
+	    genre: string;
+	    notes: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MovieMetadata(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.year = source["year"];
+	        this.series = source["series"];
+	        this.season = source["season"];
+	        this.episode = source["episode"];
+	        this.imdb_id = source["imdb_id"];
+	        this.tmdb_id = source["tmdb_id"];
+	        this.runtime_minutes = source["runtime_minutes"];
+	        this.genre = source["genre"];
+	        this.notes = source["notes"];
+	    }
+	}
 	export class Movie {
 	    id: number;
 	    title: string;
 	    default_language: string;
 	    languages: Record<string, string>;
+	    pivot_language: string;
+	    metadata: MovieMetadata;
 	    // Go type: time
 	    created_at: any;
 	    // Go type: time
@@ -111,6 +380,8 @@ export namespace backend {
 	        this.title = source["title"];
 	        this.default_language = source["default_language"];
 	        this.languages = source["languages"];
+	        this.pivot_language = source["pivot_language"];
+	        this.metadata = this.convertValues(source["metadata"], MovieMetadata);
 	        this.created_at = this.convertValues(source["created_at"], null);
 	        this.updated_at = this.convertValues(source["updated_at"], null);
 	    }
@@ -165,39 +436,108 @@ export namespace backend {
 		    return a;
 		}
 	}
+	export class MediaStream {
+	    index: number;
+	    type: string;
+	    codec: string;
+	    language: string;
+	    title: string;
+	    channels: number;
+	    default: boolean;
 	
-	export class MovieQueue {
+	    static createFrom(source: any = {}) {
+	        return new MediaStream(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.type = source["type"];
+	        this.codec = source["codec"];
+	        this.language = source["language"];
+	        this.title = source["title"];
+	        this.channels = source["channels"];
+	        this.default = source["default"];
+	    }
+	}
+	export class ModelPrice {
+	    model: string;
+	    input_per_million: number;
+	    output_per_million: number;
+	    per_audio_minute: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ModelPrice(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.model = source["model"];
+	        this.input_per_million = source["input_per_million"];
+	        this.output_per_million = source["output_per_million"];
+	        this.per_audio_minute = source["per_audio_minute"];
+	    }
+	}
+	
+	export class MovieFilter {
+	    title: string;
+	    series: string;
+	    year: number;
+	    season: number;
+	    genre: string;
+	    external_id: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MovieFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.title = source["title"];
+	        this.series = source["series"];
+	        this.year = source["year"];
+	        this.season = source["season"];
+	        this.genre = source["genre"];
+	        this.external_id = source["external_id"];
+	    }
+	}
+	export class MovieMedia {
 	    id: number;
-	    movie_id: sql.NullInt64;
-	    name: string;
-	    type: string;
+	    movie_id: number;
+	    path: string;
+	    hash: string;
 	    file_type: string;
-	    content: string;
-	    source_language: string;
-	    target_languages: Record<string, string>;
-	    status: number;
+	    size: number;
+	    duration_ms: number;
+	    framerate: number;
+	    width: number;
+	    height: number;
+	    audio_channels: number;
+	    audio_stream_index: number;
+	    primary: boolean;
 	    // Go type: time
 	    created_at: any;
-	    // Go type: time
-	    updated_at?: any;
 	
 	    static createFrom(source: any = {}) {
-	        return new MovieQueue(source);
+	        return new MovieMedia(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
-	        this.movie_id = this.convertValues(source["movie_id"], sql.NullInt64);
-	        this.name = source["name"];
-	        this.type = source["type"];
+	        this.movie_id = source["movie_id"];
+	        this.path = source["path"];
+	        this.hash = source["hash"];
 	        this.file_type = source["file_type"];
-	        this.content = source["content"];
-	        this.source_language = source["source_language"];
-	        this.target_languages = source["target_languages"];
-	        this.status = source["status"];
+	        this.size = source["size"];
+	        this.duration_ms = source["duration_ms"];
+	        this.framerate = source["framerate"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.audio_channels = source["audio_channels"];
+	        this.audio_stream_index = source["audio_stream_index"];
+	        this.primary = source["primary"];
 	        this.created_at = this.convertValues(source["created_at"], null);
-	        this.updated_at = this.convertValues(source["updated_at"], null);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -218,18 +558,29 @@ export namespace backend {
 		    return a;
 		}
 	}
-	export class MovieQueueResponse {
-	    movies: MovieQueue[];
-	    pagination: Pagination;
+	
+	export class TranslationReport {
+	    language: string;
+	    translated: number;
+	    untranslated_ids: number[];
+	    errors: BatchError[];
+	    over_limit_ids: number[];
+	    cancelled: boolean;
+	    cache_hits: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new MovieQueueResponse(source);
+	        return new TranslationReport(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.movies = this.convertValues(source["movies"], MovieQueue);
-	        this.pagination = this.convertValues(source["pagination"], Pagination);
+	        this.language = source["language"];
+	        this.translated = source["translated"];
+	        this.untranslated_ids = source["untranslated_ids"];
+	        this.errors = this.convertValues(source["errors"], BatchError);
+	        this.over_limit_ids = source["over_limit_ids"];
+	        this.cancelled = source["cancelled"];
+	        this.cache_hits = source["cache_hits"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -250,33 +601,56 @@ export namespace backend {
 		    return a;
 		}
 	}
-	
-	export class Subtitle {
+	export class MovieQueue {
 	    id: number;
-	    movie_id: number;
-	    sl_no: number;
-	    start_time: string;
-	    end_time: string;
-	    content: Record<string, string>;
+	    movie_id: sql.NullInt64;
+	    name: string;
+	    type: string;
+	    file_type: string;
+	    content: string;
+	    source_language: string;
+	    target_languages: Record<string, string>;
+	    pivot_language: string;
+	    status: number;
 	    // Go type: time
 	    created_at: any;
 	    // Go type: time
-	    updated_at: any;
+	    updated_at?: any;
+	    transcriber: string;
+	    media_path: string;
+	    media_size: number;
+	    media_hash: string;
+	    stream_index: number;
+	    detected_language: string;
+	    translate_audio: boolean;
+	    translation_errors: TranslationReport[];
 	
 	    static createFrom(source: any = {}) {
-	        return new Subtitle(source);
+	        return new MovieQueue(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
-	        this.movie_id = source["movie_id"];
-	        this.sl_no = source["sl_no"];
-	        this.start_time = source["start_time"];
-	        this.end_time = source["end_time"];
+	        this.movie_id = this.convertValues(source["movie_id"], sql.NullInt64);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.file_type = source["file_type"];
 	        this.content = source["content"];
+	        this.source_language = source["source_language"];
+	        this.target_languages = source["target_languages"];
+	        this.pivot_language = source["pivot_language"];
+	        this.status = source["status"];
 	        this.created_at = this.convertValues(source["created_at"], null);
 	        this.updated_at = this.convertValues(source["updated_at"], null);
+	        this.transcriber = source["transcriber"];
+	        this.media_path = source["media_path"];
+	        this.media_size = source["media_size"];
+	        this.media_hash = source["media_hash"];
+	        this.stream_index = source["stream_index"];
+	        this.detected_language = source["detected_language"];
+	        this.translate_audio = source["translate_audio"];
+	        this.translation_errors = this.convertValues(source["translation_errors"], TranslationReport);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -297,17 +671,17 @@ export namespace backend {
 		    return a;
 		}
 	}
-	export class SubtitleResponse {
-	    subtitles: Subtitle[];
+	export class MovieQueueResponse {
+	    movies: MovieQueue[];
 	    pagination: Pagination;
 	
 	    static createFrom(source: any = {}) {
-	        return new SubtitleResponse(source);
+	        return new MovieQueueResponse(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.subtitles = this.convertValues(source["subtitles"], Subtitle);
+	        this.movies = this.convertValues(source["movies"], MovieQueue);
 	        this.pagination = this.convertValues(source["pagination"], Pagination);
 	    }
 	
@@ -329,6 +703,591 @@ export namespace backend {
 		    return a;
 		}
 	}
+	
+	export class QualityReport {
+	    language: string;
+	    method: string;
+	    checked: number;
+	    flagged_ids: number[];
+	    average_score: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new QualityReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.language = source["language"];
+	        this.method = source["method"];
+	        this.checked = source["checked"];
+	        this.flagged_ids = source["flagged_ids"];
+	        this.average_score = source["average_score"];
+	    }
+	}
+	export class QualityScore {
+	    score: number;
+	    method: string;
+	    flagged: boolean;
+	    note: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new QualityScore(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.score = source["score"];
+	        this.method = source["method"];
+	        this.flagged = source["flagged"];
+	        this.note = source["note"];
+	    }
+	}
+	export class QualitySettings {
+	    method: string;
+	    min_score: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new QualitySettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.method = source["method"];
+	        this.min_score = source["min_score"];
+	    }
+	}
+	export class SegmentationSettings {
+	    max_cue_duration_ms: number;
+	    min_gap_ms: number;
+	    pause_ms: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SegmentationSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.max_cue_duration_ms = source["max_cue_duration_ms"];
+	        this.min_gap_ms = source["min_gap_ms"];
+	        this.pause_ms = source["pause_ms"];
+	    }
+	}
+	export class SnapToSpeechRequest {
+	    movie_id: number;
+	    tolerance_ms: number;
+	    dry_run: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SnapToSpeechRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.movie_id = source["movie_id"];
+	        this.tolerance_ms = source["tolerance_ms"];
+	        this.dry_run = source["dry_run"];
+	    }
+	}
+	export class SpeakerCount {
+	    speaker: string;
+	    cues: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SpeakerCount(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.speaker = source["speaker"];
+	        this.cues = source["cues"];
+	    }
+	}
+	export class StoredMedia {
+	    hash: string;
+	    size: number;
+	    file_type: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new StoredMedia(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hash = source["hash"];
+	        this.size = source["size"];
+	        this.file_type = source["file_type"];
+	    }
+	}
+	export class Subtitle {
+	    id: number;
+	    movie_id: number;
+	    sl_no: number;
+	    start_time: string;
+	    end_time: string;
+	    content: Record<string, string>;
+	    quality: Record<string, QualityScore>;
+	    speaker: string;
+	    translation_chain: Record<string, string[]>;
+	    candidates: Record<string, TranslationCandidate[]>;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    updated_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Subtitle(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.movie_id = source["movie_id"];
+	        this.sl_no = source["sl_no"];
+	        this.start_time = source["start_time"];
+	        this.end_time = source["end_time"];
+	        this.content = source["content"];
+	        this.quality = this.convertValues(source["quality"], QualityScore, true);
+	        this.speaker = source["speaker"];
+	        this.translation_chain = source["translation_chain"];
+	        this.candidates = this.convertValues(source["candidates"], TranslationCandidate[], true);
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SubtitleConstraints {
+	    max_chars_per_line: number;
+	    max_lines: number;
+	    max_cps: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleConstraints(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.max_chars_per_line = source["max_chars_per_line"];
+	        this.max_lines = source["max_lines"];
+	        this.max_cps = source["max_cps"];
+	    }
+	}
+	export class SubtitleHistory {
+	    id: number;
+	    subtitle_id: number;
+	    language: string;
+	    previous_text: string;
+	    new_text: string;
+	    reason: string;
+	    instruction: string;
+	    // Go type: time
+	    created_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleHistory(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.subtitle_id = source["subtitle_id"];
+	        this.language = source["language"];
+	        this.previous_text = source["previous_text"];
+	        this.new_text = source["new_text"];
+	        this.reason = source["reason"];
+	        this.instruction = source["instruction"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SubtitleResponse {
+	    subtitles: Subtitle[];
+	    pagination: Pagination;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubtitleResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.subtitles = this.convertValues(source["subtitles"], Subtitle);
+	        this.pagination = this.convertValues(source["pagination"], Pagination);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SyncAnchor {
+	    time_ms: number;
+	    offset_ms: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SyncAnchor(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time_ms = source["time_ms"];
+	        this.offset_ms = source["offset_ms"];
+	    }
+	}
+	export class TimingChange {
+	    id: number;
+	    sl_no: number;
+	    previous_start_time: string;
+	    previous_end_time: string;
+	    start_time: string;
+	    end_time: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TimingChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.sl_no = source["sl_no"];
+	        this.previous_start_time = source["previous_start_time"];
+	        this.previous_end_time = source["previous_end_time"];
+	        this.start_time = source["start_time"];
+	        this.end_time = source["end_time"];
+	    }
+	}
+	export class SyncReport {
+	    offset_ms: number;
+	    scale: number;
+	    anchors: SyncAnchor[];
+	    checked: number;
+	    changes: TimingChange[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SyncReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.offset_ms = source["offset_ms"];
+	        this.scale = source["scale"];
+	        this.anchors = this.convertValues(source["anchors"], SyncAnchor);
+	        this.checked = source["checked"];
+	        this.changes = this.convertValues(source["changes"], TimingChange);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class TimingIssue {
+	    id: number;
+	    sl_no: number;
+	    start_time: string;
+	    end_time: string;
+	    issue: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TimingIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.sl_no = source["sl_no"];
+	        this.start_time = source["start_time"];
+	        this.end_time = source["end_time"];
+	        this.issue = source["issue"];
+	    }
+	}
+	export class TimingReport {
+	    checked: number;
+	    changes: TimingChange[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TimingReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.checked = source["checked"];
+	        this.changes = this.convertValues(source["changes"], TimingChange);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TimingValidation {
+	    checked: number;
+	    media_duration_ms: number;
+	    issues: TimingIssue[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TimingValidation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.checked = source["checked"];
+	        this.media_duration_ms = source["media_duration_ms"];
+	        this.issues = this.convertValues(source["issues"], TimingIssue);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TranscriberSettings {
+	    default: string;
+	    server_url: string;
+	    server_model: string;
+	    executable: string;
+	    arguments: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TranscriberSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.default = source["default"];
+	        this.server_url = source["server_url"];
+	        this.server_model = source["server_model"];
+	        this.executable = source["executable"];
+	        this.arguments = source["arguments"];
+	    }
+	}
+	export class TranslateSelectionRequest {
+	    movie_id: number;
+	    source_language: string;
+	    target_language: string;
+	    from_sl_no: number;
+	    to_sl_no: number;
+	    ids: number[];
+	    overwrite: boolean;
+	    instruction: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TranslateSelectionRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.movie_id = source["movie_id"];
+	        this.source_language = source["source_language"];
+	        this.target_language = source["target_language"];
+	        this.from_sl_no = source["from_sl_no"];
+	        this.to_sl_no = source["to_sl_no"];
+	        this.ids = source["ids"];
+	        this.overwrite = source["overwrite"];
+	        this.instruction = source["instruction"];
+	    }
+	}
+	export class TranslationCandidate {
+	    text: string;
+	    model: string;
+	    chain: string[];
+	    over_limit: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TranslationCandidate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.text = source["text"];
+	        this.model = source["model"];
+	        this.chain = source["chain"];
+	        this.over_limit = source["over_limit"];
+	    }
+	}
+	
+	export class UsageTotal {
+	    language: string;
+	    model: string;
+	    operation: string;
+	    requests: number;
+	    prompt_tokens: number;
+	    completion_tokens: number;
+	    audio_seconds: number;
+	    cost: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new UsageTotal(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.language = source["language"];
+	        this.model = source["model"];
+	        this.operation = source["operation"];
+	        this.requests = source["requests"];
+	        this.prompt_tokens = source["prompt_tokens"];
+	        this.completion_tokens = source["completion_tokens"];
+	        this.audio_seconds = source["audio_seconds"];
+	        this.cost = source["cost"];
+	    }
+	}
+	export class UsageSummary {
+	    totals: UsageTotal[];
+	    cost: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new UsageSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.totals = this.convertValues(source["totals"], UsageTotal);
+	        this.cost = source["cost"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class Waveform {
+	    from_ms: number;
+	    to_ms: number;
+	    duration_ms: number;
+	    min: number[];
+	    max: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Waveform(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.from_ms = source["from_ms"];
+	        this.to_ms = source["to_ms"];
+	        this.duration_ms = source["duration_ms"];
+	        this.min = source["min"];
+	        this.max = source["max"];
+	    }
+	}
+	export class WaveformRequest {
+	    movie_id: number;
+	    from_ms: number;
+	    to_ms: number;
+	    peaks: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new WaveformRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.movie_id = source["movie_id"];
+	        this.from_ms = source["from_ms"];
+	        this.to_ms = source["to_ms"];
+	        this.peaks = source["peaks"];
+	    }
+	}
 
 }
 
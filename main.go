@@ -20,6 +20,9 @@ func main() {
 	subtitle := backend.NewSubtitle()
 	setting := backend.NewSetting()
 	movieQueue := backend.NewMovieQueue()
+	usage := backend.NewUsage()
+	job := backend.NewJob()
+	cache := backend.NewCache()
 
 	// Create application with options
 	err := wails.Run(&options.App{
@@ -29,6 +32,8 @@ func main() {
 		Height:     768,
 		AssetServer: &assetserver.Options{
 			Assets: assets,
+			// Serves movie media to the preview player
+			Handler: backend.NewMediaHandler(),
 		},
 		BackgroundColour: &options.RGBA{R: 173, G: 216, B: 230, A: 1},
 		OnStartup:        app.startup,
@@ -40,6 +45,9 @@ func main() {
 			subtitle,
 			setting,
 			movieQueue,
+			usage,
+			job,
+			cache,
 		},
 		AlwaysOnTop: false,
 	})